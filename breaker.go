@@ -0,0 +1,301 @@
+package snorlax
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned, without sending the request, when the client's
+// CircuitBreaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a single circuit in a CircuitBreaker.
+type CircuitState int
+
+const (
+	// StateClosed allows all requests through, while counting failures.
+	StateClosed CircuitState = iota
+
+	// StateHalfOpen allows a limited number of probe requests through to
+	// test whether the upstream has recovered.
+	StateHalfOpen
+
+	// StateOpen rejects all requests with ErrCircuitOpen.
+	StateOpen
+)
+
+// String satisfies the fmt.Stringer interface.
+func (s CircuitState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker stops requests from being sent to upstreams which are
+// failing, giving them time to recover. A circuit opens when the ratio of
+// failed requests within a window exceeds FailureRatio, rejects requests while
+// open, and then lets probe requests through to decide whether to close again.
+// A CircuitBreaker is safe for concurrent use.
+type CircuitBreaker struct {
+	// FailureRatio is the ratio of failed requests, between 0 and 1, at which
+	// the circuit opens. A circuit never opens without at least one failure.
+	// If zero, 0.5 is used.
+	FailureRatio float64
+
+	// MinRequests is the minimum number of requests within a window before
+	// the circuit is allowed to open. If zero, 10 is used.
+	MinRequests int
+
+	// Window is the interval after which the failure counts of a closed
+	// circuit are reset.
+	Window time.Duration
+
+	// OpenDuration is how long the circuit stays open before allowing probe
+	// requests through. If zero, 30 seconds is used.
+	OpenDuration time.Duration
+
+	// HalfOpenProbes is the number of probe requests which must succeed for
+	// a half-open circuit to close. A single failed probe reopens it.
+	HalfOpenProbes int
+
+	// IsFailure optionally overrides which outcomes count as failures. By
+	// default errors and 5XX responses are failures.
+	IsFailure func(res *http.Response, err error) bool
+
+	// KeyFunc optionally partitions requests into separate circuits. Use
+	// ByHost to break the circuit for each host separately. By default all
+	// requests share a single circuit.
+	KeyFunc func(r *http.Request) string
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// Defaults used by NewCircuitBreaker, and in place of the zero value of the
+// corresponding CircuitBreaker fields.
+const (
+	defaultFailureRatio = 0.5
+	defaultMinRequests  = 10
+	defaultOpenDuration = 30 * time.Second
+)
+
+// NewCircuitBreaker constructs a CircuitBreaker which opens for 30 seconds when
+// half of at least 10 requests within a minute fail.
+func NewCircuitBreaker() *CircuitBreaker {
+	return &CircuitBreaker{
+		FailureRatio:   defaultFailureRatio,
+		MinRequests:    defaultMinRequests,
+		Window:         time.Minute,
+		OpenDuration:   defaultOpenDuration,
+		HalfOpenProbes: 1,
+		circuits:       make(map[string]*circuit),
+	}
+}
+
+// State returns the current state of the circuit for key.
+func (b *CircuitBreaker) State(key string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[key]
+	if !ok {
+		return StateClosed
+	}
+
+	return c.state
+}
+
+// transition describes a change in the state of a circuit.
+type transition struct {
+	key      string
+	from, to CircuitState
+}
+
+// key returns the circuit key for the request.
+func (b *CircuitBreaker) key(r *http.Request) string {
+	if b.KeyFunc == nil {
+		return ""
+	}
+
+	return b.KeyFunc(r)
+}
+
+// allow returns ErrCircuitOpen if the request may not be sent. It also reports
+// the transition of an open circuit to half-open once it has cooled down.
+func (b *CircuitBreaker) allow(key string, now time.Time) (*transition,
+	error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(key, now)
+
+	var t *transition
+	if c.state == StateOpen && now.Sub(c.openedAt) >= b.openDuration() {
+		t = c.setState(key, StateHalfOpen, now)
+	}
+
+	switch c.state {
+	case StateOpen:
+		return t, ErrCircuitOpen
+	case StateHalfOpen:
+		if c.inFlight >= b.probes() {
+			return t, ErrCircuitOpen
+		}
+		c.inFlight++
+	}
+
+	return t, nil
+}
+
+// record counts the outcome of a request which was allowed through, and
+// reports any resulting transition.
+func (b *CircuitBreaker) record(key string, res *http.Response, err error,
+	now time.Time) *transition {
+	failed := err != nil || res.StatusCode >= http.StatusInternalServerError
+	if b.IsFailure != nil {
+		failed = b.IsFailure(res, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(key, now)
+
+	// Requests which never reached the upstream say nothing about its health,
+	// so they only release their probe slot.
+	var limitErr *RateLimitedError
+	if errors.As(err, &limitErr) || errors.Is(err, context.Canceled) {
+		if c.state == StateHalfOpen && c.inFlight > 0 {
+			c.inFlight--
+		}
+		return nil
+	}
+
+	switch c.state {
+	case StateHalfOpen:
+		if c.inFlight > 0 {
+			c.inFlight--
+		}
+
+		if failed {
+			return c.setState(key, StateOpen, now)
+		}
+
+		c.successes++
+		if c.successes >= b.probes() {
+			return c.setState(key, StateClosed, now)
+		}
+	case StateClosed:
+		if b.Window > 0 && now.Sub(c.windowStart) >= b.Window {
+			c.reset(now)
+		}
+
+		c.requests++
+		if failed {
+			c.failures++
+		}
+
+		if c.failures > 0 && c.requests >= b.minRequests() &&
+			float64(c.failures)/float64(c.requests) >= b.failureRatio() {
+			return c.setState(key, StateOpen, now)
+		}
+	}
+
+	return nil
+}
+
+// circuit returns the circuit for key, creating a closed one if needed. The
+// caller must hold b.mu.
+func (b *CircuitBreaker) circuit(key string, now time.Time) *circuit {
+	if b.circuits == nil {
+		b.circuits = make(map[string]*circuit)
+	}
+
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{state: StateClosed, windowStart: now}
+		b.circuits[key] = c
+	}
+
+	return c
+}
+
+// failureRatio returns the ratio of failed requests at which a circuit opens.
+func (b *CircuitBreaker) failureRatio() float64 {
+	if b.FailureRatio <= 0 {
+		return defaultFailureRatio
+	}
+
+	return b.FailureRatio
+}
+
+// minRequests returns the number of requests needed before a circuit opens.
+func (b *CircuitBreaker) minRequests() int {
+	if b.MinRequests < 1 {
+		return defaultMinRequests
+	}
+
+	return b.MinRequests
+}
+
+// openDuration returns how long a circuit stays open.
+func (b *CircuitBreaker) openDuration() time.Duration {
+	if b.OpenDuration <= 0 {
+		return defaultOpenDuration
+	}
+
+	return b.OpenDuration
+}
+
+// probes returns the number of probe requests allowed while half-open.
+func (b *CircuitBreaker) probes() int {
+	if b.HalfOpenProbes < 1 {
+		return 1
+	}
+
+	return b.HalfOpenProbes
+}
+
+// circuit holds the state of a single circuit.
+type circuit struct {
+	state       CircuitState
+	openedAt    time.Time
+	windowStart time.Time
+	requests    int
+	failures    int
+	successes   int
+	inFlight    int
+}
+
+// setState moves the circuit into state, resetting its counters.
+func (c *circuit) setState(key string, state CircuitState,
+	now time.Time) *transition {
+	t := transition{key: key, from: c.state, to: state}
+
+	c.state = state
+	c.reset(now)
+	if state == StateOpen {
+		c.openedAt = now
+	}
+
+	return &t
+}
+
+// reset clears the circuit's counters and starts a new window.
+func (c *circuit) reset(now time.Time) {
+	c.windowStart = now
+	c.requests = 0
+	c.failures = 0
+	c.successes = 0
+	c.inFlight = 0
+}
@@ -0,0 +1,140 @@
+package snorlax_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/stretchr/testify/suite"
+)
+
+type CircuitBreakerTestSuite struct {
+	suite.Suite
+	breaker  *snorlax.CircuitBreaker
+	client   snorlax.Client
+	healthy  int32
+	requests int32
+	server   *httptest.Server
+}
+
+func (suite *CircuitBreakerTestSuite) SetupTest() {
+	atomic.StoreInt32(&suite.healthy, 0)
+	atomic.StoreInt32(&suite.requests, 0)
+
+	h := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&suite.requests, 1)
+		if atomic.LoadInt32(&suite.healthy) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+
+	suite.server = httptest.NewServer(http.HandlerFunc(h))
+
+	suite.breaker = snorlax.NewCircuitBreaker()
+	suite.breaker.MinRequests = 2
+	suite.breaker.OpenDuration = 50 * time.Millisecond
+
+	suite.client = snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		SetCircuitBreaker(suite.breaker)
+}
+
+func (suite *CircuitBreakerTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+// trip sends failing requests until the circuit opens.
+func (suite *CircuitBreakerTestSuite) trip() {
+	for i := 0; i < 2; i++ {
+		res, err := suite.client.Get(context.TODO(), "/breaker", nil)
+		suite.Require().NoError(err)
+		suite.Require().Equal(http.StatusInternalServerError, res.StatusCode)
+	}
+
+	suite.Require().Equal(snorlax.StateOpen, suite.breaker.State(""))
+}
+
+func (suite *CircuitBreakerTestSuite) TestCircuitBreaker_Opens() {
+	suite.trip()
+
+	_, err := suite.client.Get(context.TODO(), "/breaker", nil)
+	suite.Require().True(errors.Is(err, snorlax.ErrCircuitOpen))
+	suite.Require().EqualValues(2, atomic.LoadInt32(&suite.requests))
+}
+
+func (suite *CircuitBreakerTestSuite) TestCircuitBreaker_Recovers() {
+	suite.trip()
+	atomic.StoreInt32(&suite.healthy, 1)
+	time.Sleep(60 * time.Millisecond)
+
+	res, err := suite.client.Get(context.TODO(), "/breaker", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, res.StatusCode)
+	suite.Require().Equal(snorlax.StateClosed, suite.breaker.State(""))
+}
+
+func (suite *CircuitBreakerTestSuite) TestCircuitBreaker_ProbeFails() {
+	suite.trip()
+	time.Sleep(60 * time.Millisecond)
+
+	res, err := suite.client.Get(context.TODO(), "/breaker", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusInternalServerError, res.StatusCode)
+	suite.Require().Equal(snorlax.StateOpen, suite.breaker.State(""))
+
+	_, err = suite.client.Get(context.TODO(), "/breaker", nil)
+	suite.Require().True(errors.Is(err, snorlax.ErrCircuitOpen))
+}
+
+func (suite *CircuitBreakerTestSuite) TestCircuitBreaker_MinRequests() {
+	suite.breaker.MinRequests = 5
+
+	for i := 0; i < 4; i++ {
+		_, err := suite.client.Get(context.TODO(), "/breaker", nil)
+		suite.Require().NoError(err)
+	}
+
+	suite.Require().Equal(snorlax.StateClosed, suite.breaker.State(""))
+}
+
+func (suite *CircuitBreakerTestSuite) TestCircuitBreaker_ZeroValue() {
+	atomic.StoreInt32(&suite.healthy, 1)
+
+	// Unset fields take the defaults of NewCircuitBreaker, and successful
+	// requests never open the circuit.
+	breaker := &snorlax.CircuitBreaker{OpenDuration: time.Second}
+	client := suite.client.Clone().SetCircuitBreaker(breaker)
+
+	for i := 0; i < 20; i++ {
+		res, err := client.Get(context.TODO(), "/breaker", nil)
+		suite.Require().NoError(err)
+		suite.Require().Equal(http.StatusOK, res.StatusCode)
+	}
+	suite.Require().Equal(snorlax.StateClosed, breaker.State(""))
+
+	// At least MinRequests are needed before the circuit opens.
+	atomic.StoreInt32(&suite.healthy, 0)
+	breaker = &snorlax.CircuitBreaker{}
+	client = suite.client.Clone().SetCircuitBreaker(breaker)
+	for i := 0; i < 9; i++ {
+		_, err := client.Get(context.TODO(), "/breaker", nil)
+		suite.Require().NoError(err)
+	}
+	suite.Require().Equal(snorlax.StateClosed, breaker.State(""))
+
+	_, err := client.Get(context.TODO(), "/breaker", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(snorlax.StateOpen, breaker.State(""))
+}
+
+func TestCircuitBreakerTestSuite(t *testing.T) {
+	suite.Run(t, new(CircuitBreakerTestSuite))
+}
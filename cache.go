@@ -0,0 +1,413 @@
+package snorlax
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheStatus describes how a Cache produced a response.
+type CacheStatus string
+
+const (
+	// CacheNone means the response did not pass through a Cache, either
+	// because none is configured or because the request isn't cacheable.
+	CacheNone CacheStatus = ""
+
+	// CacheMiss means the response was fetched from the upstream.
+	CacheMiss CacheStatus = "miss"
+
+	// CacheHit means the response was served from the cache without
+	// contacting the upstream.
+	CacheHit CacheStatus = "hit"
+
+	// CacheRevalidated means a stale cached response was served after the
+	// upstream confirmed that it is still valid.
+	CacheRevalidated CacheStatus = "revalidated"
+)
+
+// cacheableStatusCodes lists the status codes which RFC 7231 defines as
+// cacheable by default.
+var cacheableStatusCodes = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// Cache is an RFC 7234 HTTP cache for GET requests. It honors Cache-Control,
+// Expires and Vary, and revalidates stale responses using their ETag or
+// Last-Modified validators. Successful unsafe requests invalidate the cached
+// response for their URL.
+//
+// Entries are keyed by URL, so a Cache may be shared by requests made on behalf
+// of different users. Responses to requests carrying credentials, in an
+// Authorization or Cookie header, are only stored, and such requests are only
+// served from the cache, if the responses are explicitly shareable, as RFC
+// 7234 section 3.2 requires of shared caches.
+type Cache struct {
+	store CacheStore
+}
+
+// NewCache constructs a Cache which keeps its entries in store.
+func NewCache(store CacheStore) *Cache {
+	return &Cache{store: store}
+}
+
+// cacheEntry is the serialized form of a cached response.
+type cacheEntry struct {
+	Response     []byte              `json:"response"`
+	RequestTime  time.Time           `json:"request_time"`
+	ResponseTime time.Time           `json:"response_time"`
+	Vary         map[string][]string `json:"vary,omitempty"`
+}
+
+// do serves the request from the cache where possible, calling next to fetch
+// or revalidate responses from the upstream. It returns how the response was
+// produced.
+func (c *Cache) do(req *http.Request, next func(*http.Request) (*http.Response,
+	error), logger logEntry) (*http.Response, CacheStatus, error) {
+	key := req.URL.String()
+
+	if req.Method != http.MethodGet {
+		res, err := next(req)
+		if err == nil && isUnsafe(req.Method) && res.StatusCode < 400 {
+			if err := c.store.Delete(key); err != nil {
+				logger.WithError(err).
+					Warn("failed to invalidate cache entry")
+			}
+		}
+		return res, CacheNone, err
+	}
+
+	// Requests which opt out of caching, or carry their own validators, are
+	// passed straight through.
+	reqCC := parseCacheControl(req.Header)
+	if reqCC.has("no-store") || req.Header.Get("If-None-Match") != "" ||
+		req.Header.Get("If-Modified-Since") != "" {
+		res, err := next(req)
+		return res, CacheNone, err
+	}
+
+	entry, cached := c.lookup(key, req, logger)
+	if cached != nil && hasCredentials(req) && !shareable(cached) {
+		cached.Body.Close()
+		cached = nil
+	}
+
+	if cached != nil {
+		resCC := parseCacheControl(cached.Header)
+		now := time.Now()
+		age := currentAge(cached, entry, now)
+
+		fresh := age < freshness(cached, resCC, entry.ResponseTime)
+		if maxAge, ok := reqCC.seconds("max-age"); ok && age > maxAge {
+			fresh = false
+		}
+
+		if fresh && !reqCC.has("no-cache") && !resCC.has("no-cache") {
+			logger.WithURL("url", req.URL).Debug("serving response from cache")
+			return cached, CacheHit, nil
+		}
+
+		condReq := req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			condReq.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := cached.Header.Get("Last-Modified"); lastModified != "" {
+			condReq.Header.Set("If-Modified-Since", lastModified)
+		}
+
+		requestTime := time.Now()
+		res, err := next(condReq)
+		if err != nil {
+			cached.Body.Close()
+			return nil, CacheNone, err
+		}
+
+		if res.StatusCode == http.StatusNotModified {
+			_, _ = io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+
+			for k, v := range res.Header {
+				if k != "Content-Length" && k != "Transfer-Encoding" {
+					cached.Header[k] = v
+				}
+			}
+
+			c.save(key, req, cached, requestTime, time.Now(), logger)
+			logger.WithURL("url", req.URL).Debug("cached response revalidated")
+			return cached, CacheRevalidated, nil
+		}
+
+		cached.Body.Close()
+		c.save(key, req, res, requestTime, time.Now(), logger)
+		return res, CacheMiss, nil
+	}
+
+	requestTime := time.Now()
+	res, err := next(req)
+	if err != nil {
+		return nil, CacheNone, err
+	}
+
+	c.save(key, req, res, requestTime, time.Now(), logger)
+	return res, CacheMiss, nil
+}
+
+// lookup returns the cached response for the request, or nil if there is no
+// entry which matches the request's Vary headers.
+func (c *Cache) lookup(key string, req *http.Request,
+	logger logEntry) (*cacheEntry, *http.Response) {
+	data, ok, err := c.store.Get(key)
+	if err != nil {
+		logger.WithError(err).Warn("failed to read cache")
+		return nil, nil
+	} else if !ok {
+		return nil, nil
+	}
+
+	var entry cacheEntry
+	if err = json.Unmarshal(data, &entry); err != nil {
+		logger.WithError(err).Warn("invalid cache entry")
+		return nil, nil
+	}
+
+	for name, values := range entry.Vary {
+		if strings.Join(req.Header.Values(name), ",") !=
+			strings.Join(values, ",") {
+			return nil, nil
+		}
+	}
+
+	res, err := http.ReadResponse(bufio.NewReader(
+		bytes.NewReader(entry.Response)), req)
+	if err != nil {
+		logger.WithError(err).Warn("invalid cache entry")
+		return nil, nil
+	}
+
+	return &entry, res
+}
+
+// save stores the response if it is cacheable. The response body is read into
+// memory, and replaced so that the caller can still read it.
+func (c *Cache) save(key string, req *http.Request, res *http.Response,
+	requestTime, responseTime time.Time, logger logEntry) {
+	if !storable(res) || (hasCredentials(req) && !shareable(res)) {
+		return
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		res.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body),
+			errReader{err}))
+		return
+	}
+
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	res.ContentLength = int64(len(body))
+	res.TransferEncoding = nil
+
+	dump, err := httputil.DumpResponse(res, true)
+	if err != nil {
+		logger.WithError(err).Warn("failed to cache response")
+		return
+	}
+
+	entry := cacheEntry{
+		Response:     dump,
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+	}
+
+	for _, name := range headerList(res.Header, "Vary") {
+		if entry.Vary == nil {
+			entry.Vary = make(map[string][]string)
+		}
+		entry.Vary[http.CanonicalHeaderKey(name)] = req.Header.Values(name)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		logger.WithError(err).Warn("failed to cache response")
+		return
+	}
+
+	if err = c.store.Set(key, data); err != nil {
+		logger.WithError(err).Warn("failed to cache response")
+	}
+}
+
+// storable returns whether a private cache is allowed to store the response.
+func storable(res *http.Response) bool {
+	if !cacheableStatusCodes[res.StatusCode] {
+		return false
+	}
+
+	cc := parseCacheControl(res.Header)
+	if cc.has("no-store") {
+		return false
+	}
+
+	for _, name := range headerList(res.Header, "Vary") {
+		if name == "*" {
+			return false
+		}
+	}
+
+	_, hasMaxAge := cc.seconds("max-age")
+	return hasMaxAge || res.Header.Get("Expires") != "" ||
+		res.Header.Get("ETag") != "" || res.Header.Get("Last-Modified") != ""
+}
+
+// hasCredentials returns whether the request carries credentials, which may
+// make the response to it specific to a user.
+func hasCredentials(req *http.Request) bool {
+	return req.Header.Get("Authorization") != "" ||
+		req.Header.Get("Cookie") != ""
+}
+
+// shareable returns whether the response may be served to any user, even
+// though it was requested with credentials.
+func shareable(res *http.Response) bool {
+	cc := parseCacheControl(res.Header)
+	if cc.has("private") {
+		return false
+	}
+
+	_, hasSMaxAge := cc.seconds("s-maxage")
+	return cc.has("public") || hasSMaxAge
+}
+
+// freshness returns the freshness lifetime of the response, as defined by RFC
+// 7234 section 4.2.1.
+func freshness(res *http.Response, cc cacheControl,
+	responseTime time.Time) time.Duration {
+	if maxAge, ok := cc.seconds("max-age"); ok {
+		return maxAge
+	}
+
+	expires, err := http.ParseTime(res.Header.Get("Expires"))
+	if err != nil {
+		return 0
+	}
+
+	date, err := http.ParseTime(res.Header.Get("Date"))
+	if err != nil {
+		date = responseTime
+	}
+
+	return expires.Sub(date)
+}
+
+// currentAge returns the age of the cached response, as defined by RFC 7234
+// section 4.2.3.
+func currentAge(res *http.Response, entry *cacheEntry,
+	now time.Time) time.Duration {
+	var apparentAge time.Duration
+	if date, err := http.ParseTime(res.Header.Get("Date")); err == nil {
+		if d := entry.ResponseTime.Sub(date); d > 0 {
+			apparentAge = d
+		}
+	}
+
+	var ageValue time.Duration
+	if seconds, err := strconv.Atoi(res.Header.Get("Age")); err == nil {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+
+	correctedAge := ageValue + entry.ResponseTime.Sub(entry.RequestTime)
+	if apparentAge > correctedAge {
+		correctedAge = apparentAge
+	}
+
+	return correctedAge + now.Sub(entry.ResponseTime)
+}
+
+// isUnsafe returns whether the method may change state on the upstream.
+func isUnsafe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions,
+		http.MethodTrace:
+		return false
+	default:
+		return true
+	}
+}
+
+// cacheControl holds the parsed directives of a Cache-Control header.
+type cacheControl map[string]string
+
+// parseCacheControl parses the Cache-Control directives in the headers.
+func parseCacheControl(h http.Header) cacheControl {
+	cc := make(cacheControl)
+	for _, directive := range headerList(h, "Cache-Control") {
+		name, value := directive, ""
+		if i := strings.Index(directive, "="); i >= 0 {
+			name, value = directive[:i], strings.Trim(directive[i+1:], `"`)
+		}
+		cc[strings.ToLower(strings.TrimSpace(name))] = value
+	}
+
+	return cc
+}
+
+// has returns whether the directive is present.
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+// seconds returns the value of a delta-seconds directive.
+func (cc cacheControl) seconds(directive string) (time.Duration, bool) {
+	value, ok := cc[directive]
+	if !ok {
+		return 0, false
+	}
+
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
+
+// headerList splits the comma separated values of a header into a list.
+func headerList(h http.Header, key string) []string {
+	var list []string
+	for _, v := range h.Values(key) {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+
+	return list
+}
+
+// errReader is an io.Reader which always fails with err.
+type errReader struct {
+	err error
+}
+
+// Read satisfies the io.Reader interface.
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,189 @@
+package snorlax_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/stretchr/testify/suite"
+)
+
+type CacheTestSuite struct {
+	suite.Suite
+	client   snorlax.Client
+	requests int32
+	server   *httptest.Server
+}
+
+func (suite *CacheTestSuite) SetupTest() {
+	atomic.StoreInt32(&suite.requests, 0)
+
+	h := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&suite.requests, 1)
+
+		switch r.URL.Path {
+		case "/max-age":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/etag":
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "X-Pokemon")
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+		case "/account":
+			w.Header().Set("Cache-Control", "max-age=60")
+			_, _ = w.Write([]byte("secret for " +
+				r.Header.Get("Authorization")))
+			return
+		case "/public":
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		case "/spoof":
+			w.Header().Set("X-Snorlax-Cache", "hit")
+		}
+
+		_, _ = w.Write([]byte("snorlax"))
+	}
+
+	suite.server = httptest.NewServer(http.HandlerFunc(h))
+	suite.client = snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		SetCache(snorlax.NewCache(snorlax.NewMemoryCache(10)))
+}
+
+func (suite *CacheTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+// get performs a GET request, checking the response body and cache status.
+func (suite *CacheTestSuite) get(target string, status snorlax.CacheStatus,
+	hooks ...snorlax.RequestHook) {
+	res, err := suite.client.Get(context.TODO(), target, nil, hooks...)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, res.StatusCode)
+	suite.Require().Equal(status, res.CacheStatus())
+
+	body, err := ioutil.ReadAll(res.Body)
+	suite.Require().NoError(err)
+	suite.Require().Equal("snorlax", string(body))
+}
+
+func (suite *CacheTestSuite) TestCache_MaxAge() {
+	suite.get("/max-age", snorlax.CacheMiss)
+	suite.get("/max-age", snorlax.CacheHit)
+	suite.Require().EqualValues(1, atomic.LoadInt32(&suite.requests))
+}
+
+func (suite *CacheTestSuite) TestCache_Revalidate() {
+	suite.get("/etag", snorlax.CacheMiss)
+	suite.get("/etag", snorlax.CacheRevalidated)
+	suite.Require().EqualValues(2, atomic.LoadInt32(&suite.requests))
+}
+
+func (suite *CacheTestSuite) TestCache_Vary() {
+	suite.get("/vary", snorlax.CacheMiss, snorlax.WithHeader("X-Pokemon", "1"))
+	suite.get("/vary", snorlax.CacheHit, snorlax.WithHeader("X-Pokemon", "1"))
+	suite.get("/vary", snorlax.CacheMiss, snorlax.WithHeader("X-Pokemon", "2"))
+	suite.Require().EqualValues(2, atomic.LoadInt32(&suite.requests))
+}
+
+func (suite *CacheTestSuite) TestCache_NoStore() {
+	suite.get("/no-store", snorlax.CacheMiss)
+	suite.get("/no-store", snorlax.CacheMiss)
+	suite.Require().EqualValues(2, atomic.LoadInt32(&suite.requests))
+}
+
+func (suite *CacheTestSuite) TestCache_RequestNoCache() {
+	suite.get("/max-age", snorlax.CacheMiss)
+	suite.get("/max-age", snorlax.CacheMiss,
+		snorlax.WithHeader("Cache-Control", "no-cache"))
+	suite.Require().EqualValues(2, atomic.LoadInt32(&suite.requests))
+}
+
+func (suite *CacheTestSuite) TestCache_UnsafeInvalidates() {
+	suite.get("/max-age", snorlax.CacheMiss)
+
+	res, err := suite.client.Post(context.TODO(), "/max-age", nil, nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(snorlax.CacheNone, res.CacheStatus())
+
+	suite.get("/max-age", snorlax.CacheMiss)
+	suite.Require().EqualValues(3, atomic.LoadInt32(&suite.requests))
+}
+
+func (suite *CacheTestSuite) TestCache_Credentials() {
+	account := func(token string) string {
+		res, err := suite.client.Get(context.TODO(), "/account", nil,
+			snorlax.WithBearerToken(token))
+		suite.Require().NoError(err)
+		suite.Require().Equal(snorlax.CacheMiss, res.CacheStatus())
+
+		body, err := ioutil.ReadAll(res.Body)
+		suite.Require().NoError(err)
+		return string(body)
+	}
+
+	// Responses to requests with credentials aren't shared between users.
+	suite.Require().Equal("secret for Bearer alice", account("alice"))
+	suite.Require().Equal("secret for Bearer bob", account("bob"))
+	suite.Require().EqualValues(2, atomic.LoadInt32(&suite.requests))
+
+	// Unless they are explicitly public.
+	suite.get("/public", snorlax.CacheMiss, snorlax.WithBearerToken("alice"))
+	suite.get("/public", snorlax.CacheHit, snorlax.WithBearerToken("bob"))
+	suite.Require().EqualValues(3, atomic.LoadInt32(&suite.requests))
+}
+
+func (suite *CacheTestSuite) TestCache_StatusNotSpoofed() {
+	suite.get("/spoof", snorlax.CacheMiss)
+
+	res, err := snorlax.NewClient(snorlax.Defaults()).
+		Get(context.TODO(), suite.server.URL+"/spoof", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(snorlax.CacheNone, res.CacheStatus())
+}
+
+func (suite *CacheTestSuite) TestDiskCache() {
+	store, err := snorlax.NewDiskCache(suite.T().TempDir())
+	suite.Require().NoError(err)
+
+	suite.client.SetCache(snorlax.NewCache(store))
+	suite.get("/max-age", snorlax.CacheMiss)
+	suite.get("/max-age", snorlax.CacheHit)
+	suite.Require().EqualValues(1, atomic.LoadInt32(&suite.requests))
+
+	suite.Require().NoError(store.Delete(suite.server.URL + "/max-age"))
+	_, ok, err := store.Get(suite.server.URL + "/max-age")
+	suite.Require().NoError(err)
+	suite.Require().False(ok)
+}
+
+func (suite *CacheTestSuite) TestMemoryCache_Evicts() {
+	store := snorlax.NewMemoryCache(2)
+	suite.Require().NoError(store.Set("a", []byte("a")))
+	suite.Require().NoError(store.Set("b", []byte("b")))
+
+	_, ok, _ := store.Get("a")
+	suite.Require().True(ok)
+
+	suite.Require().NoError(store.Set("c", []byte("c")))
+	suite.Require().Equal(2, store.Len())
+
+	_, ok, _ = store.Get("b")
+	suite.Require().False(ok)
+	_, ok, _ = store.Get("a")
+	suite.Require().True(ok)
+}
+
+func TestCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(CacheTestSuite))
+}
@@ -0,0 +1,176 @@
+package snorlax
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CacheStore persists the entries of a Cache. Implementations must be safe for
+// concurrent use.
+type CacheStore interface {
+	// Get returns the entry stored under key, and whether it was found.
+	Get(key string) ([]byte, bool, error)
+
+	// Set stores the entry under key, replacing any existing entry.
+	Set(key string, entry []byte) error
+
+	// Delete removes the entry stored under key, if any.
+	Delete(key string) error
+}
+
+// MemoryCache is an in-memory CacheStore which evicts the least recently used
+// entries once it holds its maximum number of entries.
+type MemoryCache struct {
+	maxEntries int
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	lru        *list.List
+}
+
+// memoryEntry is the value held by each element of the MemoryCache's list.
+type memoryEntry struct {
+	key   string
+	value []byte
+}
+
+// NewMemoryCache constructs a MemoryCache holding up to maxEntries entries. A
+// maxEntries of zero or less means the cache is unbounded.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Get satisfies the CacheStore interface.
+func (c *MemoryCache) Get(key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	c.lru.MoveToFront(e)
+	return e.Value.(*memoryEntry).value, true, nil
+}
+
+// Set satisfies the CacheStore interface.
+func (c *MemoryCache) Set(key string, entry []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*memoryEntry).value = entry
+		c.lru.MoveToFront(e)
+		return nil
+	}
+
+	c.entries[key] = c.lru.PushFront(&memoryEntry{key: key, value: entry})
+
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryEntry).key)
+	}
+
+	return nil
+}
+
+// Delete satisfies the CacheStore interface.
+func (c *MemoryCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.lru.Remove(e)
+		delete(c.entries, key)
+	}
+
+	return nil
+}
+
+// Len returns the number of entries in the cache.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// DiskCache is a CacheStore which keeps each entry in a file in a directory.
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache constructs a DiskCache which stores entries in dir, creating it
+// if it doesn't exist.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	return &DiskCache{dir: dir}, nil
+}
+
+// Get satisfies the CacheStore interface.
+func (c *DiskCache) Get(key string) ([]byte, bool, error) {
+	data, err := ioutil.ReadFile(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	return data, true, nil
+}
+
+// Set satisfies the CacheStore interface. Entries are written to a temporary
+// file first, so that concurrent readers never observe a partial entry.
+func (c *DiskCache) Set(key string, entry []byte) error {
+	f, err := ioutil.TempFile(c.dir, ".entry-")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.Write(entry); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	if err = os.Rename(f.Name(), c.path(key)); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	return nil
+}
+
+// Delete satisfies the CacheStore interface.
+func (c *DiskCache) Delete(key string) error {
+	err := os.Remove(c.path(key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete cache entry: %w", err)
+	}
+
+	return nil
+}
+
+// path returns the file in which the entry for key is stored.
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,456 @@
+package snorlax
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrInteractionNotFound is returned when a Cassette replaying requests has
+// not recorded an interaction matching the request.
+var ErrInteractionNotFound = errors.New("no matching interaction in cassette")
+
+// CassetteMode decides whether a Cassette records requests, replays them, or
+// both.
+type CassetteMode int
+
+const (
+	// CassetteReplay serves every request from the cassette, and never
+	// contacts the upstream. Requests which don't match an interaction fail
+	// with ErrInteractionNotFound. It is the zero CassetteMode, so a Cassette
+	// never overwrites its file unless it is told to record.
+	CassetteReplay CassetteMode = iota
+
+	// CassetteRecord sends every request to the upstream and records it,
+	// replacing the cassette's existing interactions.
+	CassetteRecord
+
+	// CassetteRecordIfMissing serves requests from the cassette where
+	// possible, and records those which don't match an interaction.
+	CassetteRecordIfMissing
+
+	// CassettePassthrough sends every request to the upstream without
+	// recording it.
+	CassettePassthrough
+)
+
+// CassetteMatch decides which parts of a request must equal those of a
+// recorded interaction for it to be replayed. Values are compared after they
+// have been redacted.
+type CassetteMatch struct {
+	Method bool
+	URL    bool
+	Body   bool
+
+	// Headers lists the names of headers whose values must be equal.
+	Headers []string
+}
+
+// defaultCassetteMatch matches requests by their method and URL.
+var defaultCassetteMatch = &CassetteMatch{Method: true, URL: true}
+
+// CassetteRequest is a request recorded by a Cassette.
+type CassetteRequest struct {
+	Method       string      `json:"method"`
+	URL          string      `json:"url"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// CassetteResponse is a response recorded by a Cassette.
+type CassetteResponse struct {
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// Interaction is a request and the response it received, as recorded by a
+// Cassette. Bodies which aren't valid UTF-8 are encoded using base64, in which
+// case their BodyEncoding is "base64".
+type Interaction struct {
+	Request  CassetteRequest   `json:"request"`
+	Response *CassetteResponse `json:"response,omitempty"`
+}
+
+// Cassette records the requests a client sends, and the responses they
+// receive, to a file so that they can be replayed later without contacting the
+// upstream. The file holds one JSON encoded Interaction per line.
+//
+// A Cassette sees requests exactly as they are sent, after all of the client's
+// RequestHooks, Middleware and Signers have run. Each attempt of a retried
+// request is recorded separately. Recorded requests are replayed in the order
+// they were recorded, and the last matching interaction is replayed again once
+// they are all used.
+type Cassette struct {
+	// Path is the file the interactions are recorded to.
+	Path string
+
+	// Mode decides whether requests are recorded, replayed, or both. The
+	// zero Mode is CassetteReplay.
+	Mode CassetteMode
+
+	// Match decides which recorded interaction a request replays. A nil
+	// Match matches requests by their method and URL.
+	Match *CassetteMatch
+
+	// Redaction decides which header and query parameter values are masked
+	// before interactions are saved. A nil policy uses
+	// DefaultRedactionPolicy.
+	Redaction *RedactionPolicy
+
+	// Redact optionally masks any other secrets in an interaction, such as
+	// those in bodies, before it is saved. It is also applied to requests
+	// before they are matched against the recorded interactions, in which
+	// case the interaction's Response is nil.
+	Redact func(i *Interaction)
+
+	mu           sync.Mutex
+	loaded       bool
+	truncated    bool
+	interactions []*Interaction
+	used         []bool
+}
+
+// NewCassette constructs a Cassette which records to, or replays from, the
+// file at path.
+func NewCassette(path string, mode CassetteMode) *Cassette {
+	return &Cassette{Path: path, Mode: mode}
+}
+
+// Interactions returns the interactions the Cassette has loaded or recorded.
+func (c *Cassette) Interactions() ([]Interaction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	interactions := make([]Interaction, len(c.interactions))
+	for i, interaction := range c.interactions {
+		interactions[i] = *interaction
+	}
+
+	return interactions, nil
+}
+
+// middleware returns the Middleware which records and replays requests. It is
+// the innermost Middleware, so that it sees requests exactly as they are sent.
+func (c *Cassette) middleware(next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if c.Mode == CassettePassthrough {
+			return next.Do(req)
+		}
+
+		if c.Mode != CassetteRecord {
+			candidate, err := c.interaction(req)
+			if err != nil {
+				return nil, err
+			}
+
+			if c.Redact != nil {
+				c.Redact(candidate)
+			}
+
+			c.mu.Lock()
+			recorded, err := c.replay(candidate)
+			c.mu.Unlock()
+
+			if err == nil {
+				return recorded.response(req)
+			}
+
+			if c.Mode == CassetteReplay || !errors.Is(err,
+				ErrInteractionNotFound) {
+				return nil, err
+			}
+		}
+
+		interaction, err := c.interaction(req)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := next.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		interaction.Response = &CassetteResponse{
+			StatusCode: res.StatusCode,
+			Header:     c.redactHeader(res.Header),
+		}
+		interaction.Response.Body, interaction.Response.BodyEncoding =
+			encodeCassetteBody(body)
+		if c.Redact != nil {
+			c.Redact(interaction)
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if err := c.record(interaction); err != nil {
+			return nil, err
+		}
+
+		return res, nil
+	})
+}
+
+// interaction returns the interaction for the request, without a response,
+// with sensitive header and query parameter values masked.
+func (c *Cassette) interaction(req *http.Request) (*Interaction, error) {
+	body, err := peekBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	i := Interaction{Request: CassetteRequest{
+		Method: req.Method,
+		URL:    c.redaction().URL(req.URL),
+		Header: c.redactHeader(req.Header),
+	}}
+	i.Request.Body, i.Request.BodyEncoding = encodeCassetteBody(body)
+
+	return &i, nil
+}
+
+// replay returns the first unused recorded interaction which matches
+// candidate, or the last matching interaction if they have all been used. It
+// must be called with c.mu held.
+func (c *Cassette) replay(candidate *Interaction) (*Interaction, error) {
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	last := -1
+	for i, recorded := range c.interactions {
+		if !c.matches(recorded, candidate) {
+			continue
+		}
+
+		if !c.used[i] {
+			c.used[i] = true
+			return recorded, nil
+		}
+		last = i
+	}
+
+	if last == -1 || c.Mode == CassetteRecordIfMissing {
+		return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound,
+			candidate.Request.Method, candidate.Request.URL)
+	}
+
+	return c.interactions[last], nil
+}
+
+// matches returns whether the candidate request matches the recorded
+// interaction.
+func (c *Cassette) matches(recorded, candidate *Interaction) bool {
+	match := c.Match
+	if match == nil {
+		match = defaultCassetteMatch
+	}
+
+	r, q := recorded.Request, candidate.Request
+	if match.Method && r.Method != q.Method {
+		return false
+	}
+
+	if match.URL && r.URL != q.URL {
+		return false
+	}
+
+	if match.Body && (r.Body != q.Body || r.BodyEncoding != q.BodyEncoding) {
+		return false
+	}
+
+	for _, name := range match.Headers {
+		a, b := r.Header.Values(name), q.Header.Values(name)
+		if len(a) != len(b) {
+			return false
+		}
+
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// record appends the interaction to the cassette's file. A Cassette recording
+// every request replaces the file's existing interactions. It must be called
+// with c.mu held.
+func (c *Cassette) record(i *Interaction) error {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if c.Mode == CassetteRecord && !c.truncated {
+		flags |= os.O_TRUNC
+		c.truncated, c.loaded = true, true
+		c.interactions, c.used = nil, nil
+	} else if err := c.load(); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(i)
+	if err != nil {
+		return fmt.Errorf("failed to encode interaction: %w", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+
+	f, err := os.OpenFile(c.Path, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open cassette: %w", err)
+	}
+	defer f.Close()
+
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+
+	c.interactions = append(c.interactions, i)
+	c.used = append(c.used, true)
+	return nil
+}
+
+// load reads the cassette's file, if it hasn't already been loaded. A missing
+// file has no interactions. It must be called with c.mu held.
+func (c *Cassette) load() error {
+	if c.loaded {
+		return nil
+	}
+
+	f, err := os.Open(c.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		c.loaded = true
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to open cassette: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var i Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &i); err != nil {
+			return fmt.Errorf("failed to decode cassette %s line %d: %w",
+				c.Path, line, err)
+		}
+
+		c.interactions = append(c.interactions, &i)
+		c.used = append(c.used, false)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	c.loaded = true
+	return nil
+}
+
+// redaction returns the policy used to mask header and query parameter
+// values.
+func (c *Cassette) redaction() *RedactionPolicy {
+	if c.Redaction == nil {
+		return defaultRedaction
+	}
+
+	return c.Redaction
+}
+
+// redactHeader returns a copy of the header with sensitive values masked.
+func (c *Cassette) redactHeader(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+
+	redacted := make(http.Header, len(header))
+	for k, v := range header {
+		redacted[k] = c.redaction().Header(k, append([]string(nil), v...))
+	}
+
+	return redacted
+}
+
+// response returns the recorded response to req.
+func (i *Interaction) response(req *http.Request) (*http.Response, error) {
+	if i.Response == nil {
+		return nil, fmt.Errorf("%w: interaction for %s %s has no response",
+			ErrInteractionNotFound, i.Request.Method, i.Request.URL)
+	}
+
+	body, err := decodeCassetteBody(i.Response.Body, i.Response.BodyEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	header := i.Response.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status: strconv.Itoa(i.Response.StatusCode) + " " +
+			http.StatusText(i.Response.StatusCode),
+		StatusCode:    i.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// encodeCassetteBody returns the body as a string, encoding it using base64 if
+// it isn't valid UTF-8.
+func encodeCassetteBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+// decodeCassetteBody reverses encodeCassetteBody.
+func decodeCassetteBody(body, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return []byte(body), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(body)
+	}
+
+	return nil, fmt.Errorf("unsupported body encoding %s", encoding)
+}
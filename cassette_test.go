@@ -0,0 +1,285 @@
+package snorlax_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/nickcorin/snorlax/snorlaxtest"
+	"github.com/stretchr/testify/suite"
+)
+
+type CassetteTestSuite struct {
+	suite.Suite
+	server *snorlaxtest.Server
+	path   string
+}
+
+func (suite *CassetteTestSuite) SetupTest() {
+	suite.server = snorlaxtest.NewServer()
+	suite.path = filepath.Join(suite.T().TempDir(), "cassettes",
+		"pokemon.jsonl")
+}
+
+func (suite *CassetteTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *CassetteTestSuite) client(
+	cassette *snorlax.Cassette) snorlax.Client {
+	return suite.server.Client().SetCassette(cassette)
+}
+
+func (suite *CassetteTestSuite) get(client snorlax.Client, target string,
+	query url.Values, hooks ...snorlax.RequestHook) (int, string, error) {
+	res, err := client.Get(context.TODO(), target, query, hooks...)
+	if err != nil {
+		return 0, "", err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	suite.Require().NoError(err)
+
+	return res.StatusCode, string(body), nil
+}
+
+func (suite *CassetteTestSuite) TestRecordAndReplay() {
+	suite.server.Expect(http.MethodGet, "/pokemon/143").
+		RespondWith(snorlaxtest.Response{
+			Status: http.StatusOK,
+			Header: http.Header{"Set-Cookie": {"session=s3cr3t"}},
+			Body:   []byte("snorlax"),
+		}).
+		Once()
+
+	recorder := suite.client(snorlax.NewCassette(suite.path,
+		snorlax.CassetteRecord))
+	status, body, err := suite.get(recorder, "/pokemon/143",
+		url.Values{"token": {"s3cr3t"}},
+		snorlax.WithHeader("Authorization", "Bearer s3cr3t"))
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, status)
+	suite.Require().Equal("snorlax", body)
+	suite.Require().True(suite.server.AssertExpectations(suite.T()))
+
+	// Secrets are redacted before the cassette is saved.
+	saved, err := ioutil.ReadFile(suite.path)
+	suite.Require().NoError(err)
+	suite.Require().Equal(1, bytes.Count(saved, []byte("\n")))
+	suite.Require().NotContains(string(saved), "s3cr3t")
+	suite.Require().Contains(string(saved), "[REDACTED]")
+
+	// The upstream isn't contacted when replaying.
+	suite.server.Close()
+
+	player := suite.client(snorlax.NewCassette(suite.path,
+		snorlax.CassetteReplay))
+	status, body, err = suite.get(player, "/pokemon/143",
+		url.Values{"token": {"different"}})
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, status)
+	suite.Require().Equal("snorlax", body)
+
+	_, _, err = suite.get(player, "/pokemon/151", nil)
+	suite.Require().True(errors.Is(err, snorlax.ErrInteractionNotFound))
+}
+
+func (suite *CassetteTestSuite) TestRecordIfMissing() {
+	suite.server.Expect(http.MethodGet, "/pokemon/143").
+		Respond(http.StatusOK, "snorlax").
+		Once()
+	suite.server.Expect(http.MethodGet, "/pokemon/151").
+		Respond(http.StatusOK, "mew").
+		Once()
+
+	client := suite.client(snorlax.NewCassette(suite.path,
+		snorlax.CassetteRecordIfMissing))
+	_, body, err := suite.get(client, "/pokemon/143", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal("snorlax", body)
+
+	// A new cassette replays the recorded interaction, and records the
+	// missing one.
+	client = suite.client(snorlax.NewCassette(suite.path,
+		snorlax.CassetteRecordIfMissing))
+	_, body, err = suite.get(client, "/pokemon/143", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal("snorlax", body)
+
+	_, body, err = suite.get(client, "/pokemon/151", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal("mew", body)
+
+	suite.Require().True(suite.server.AssertExpectations(suite.T()))
+
+	interactions, err := snorlax.NewCassette(suite.path,
+		snorlax.CassetteReplay).Interactions()
+	suite.Require().NoError(err)
+	suite.Require().Len(interactions, 2)
+}
+
+func (suite *CassetteTestSuite) TestReplaySequence() {
+	suite.server.Expect(http.MethodGet, "/pokemon").
+		Respond(http.StatusServiceUnavailable, "").
+		Respond(http.StatusOK, "snorlax").
+		Times(2)
+
+	policy := snorlax.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+
+	recorder := suite.client(snorlax.NewCassette(suite.path,
+		snorlax.CassetteRecord)).SetRetryPolicy(policy)
+	_, body, err := suite.get(recorder, "/pokemon", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal("snorlax", body)
+	suite.server.Close()
+
+	// Each attempt is replayed in order, and the last is repeated.
+	player := suite.client(snorlax.NewCassette(suite.path,
+		snorlax.CassetteReplay))
+	statuses := make([]int, 3)
+	for i := range statuses {
+		statuses[i], _, err = suite.get(player, "/pokemon", nil)
+		suite.Require().NoError(err)
+	}
+
+	suite.Require().Equal([]int{http.StatusServiceUnavailable,
+		http.StatusOK, http.StatusOK}, statuses)
+}
+
+func (suite *CassetteTestSuite) TestMatch() {
+	suite.server.Expect(http.MethodPost, "/pokemon").
+		Body("snorlax").
+		Respond(http.StatusCreated, "143")
+	suite.server.Expect(http.MethodPost, "/pokemon").
+		Body("mew").
+		Respond(http.StatusCreated, "151")
+
+	cassette := snorlax.NewCassette(suite.path,
+		snorlax.CassetteRecordIfMissing)
+	cassette.Match = &snorlax.CassetteMatch{
+		Method:  true,
+		URL:     true,
+		Body:    true,
+		Headers: []string{"X-Trainer"},
+	}
+
+	post := func(name, trainer string) string {
+		res, err := suite.client(cassette).Post(context.TODO(), "/pokemon",
+			nil, strings.NewReader(name),
+			snorlax.WithHeader("X-Trainer", trainer))
+		suite.Require().NoError(err)
+
+		body, err := ioutil.ReadAll(res.Body)
+		suite.Require().NoError(err)
+		return string(body)
+	}
+
+	suite.Require().Equal("143", post("snorlax", "red"))
+	suite.Require().Equal("151", post("mew", "red"))
+	suite.Require().Equal("143", post("snorlax", "blue"))
+	suite.server.Close()
+
+	cassette = snorlax.NewCassette(suite.path, snorlax.CassetteReplay)
+	cassette.Match = &snorlax.CassetteMatch{Method: true, Body: true,
+		Headers: []string{"X-Trainer"}}
+	suite.Require().Equal("151", post("mew", "red"))
+	suite.Require().Equal("143", post("snorlax", "blue"))
+
+	_, err := suite.client(cassette).Post(context.TODO(), "/pokemon", nil,
+		strings.NewReader("mew"), snorlax.WithHeader("X-Trainer", "blue"))
+	suite.Require().True(errors.Is(err, snorlax.ErrInteractionNotFound))
+}
+
+func (suite *CassetteTestSuite) TestRedact() {
+	binary := []byte{0xff, 0xfe, 0x00, 0x8f}
+	suite.server.Expect(http.MethodPost, "/login").
+		RespondWith(snorlaxtest.Response{Body: binary})
+
+	redact := func(i *snorlax.Interaction) {
+		i.Request.Body = strings.ReplaceAll(i.Request.Body, "hunter2",
+			"[REDACTED]")
+	}
+
+	cassette := snorlax.NewCassette(suite.path, snorlax.CassetteRecord)
+	cassette.Redact = redact
+	_, err := suite.client(cassette).Post(context.TODO(), "/login", nil,
+		strings.NewReader(`{"password": "hunter2"}`))
+	suite.Require().NoError(err)
+
+	saved, err := ioutil.ReadFile(suite.path)
+	suite.Require().NoError(err)
+	suite.Require().NotContains(string(saved), "hunter2")
+	suite.Require().Contains(string(saved), `"body_encoding":"base64"`)
+	suite.server.Close()
+
+	// Requests are redacted in the same way before they are matched.
+	cassette = snorlax.NewCassette(suite.path, snorlax.CassetteReplay)
+	cassette.Match = &snorlax.CassetteMatch{Method: true, URL: true,
+		Body: true}
+	cassette.Redact = redact
+	res, err := suite.client(cassette).Post(context.TODO(), "/login", nil,
+		strings.NewReader(`{"password": "hunter2"}`))
+	suite.Require().NoError(err)
+
+	body, err := ioutil.ReadAll(res.Body)
+	suite.Require().NoError(err)
+	suite.Require().Equal(binary, body)
+}
+
+func (suite *CassetteTestSuite) TestPassthrough() {
+	suite.server.Expect(http.MethodGet, "/pokemon").
+		Respond(http.StatusOK, "snorlax").
+		Times(2)
+
+	client := suite.client(snorlax.NewCassette(suite.path,
+		snorlax.CassettePassthrough))
+	for i := 0; i < 2; i++ {
+		_, _, err := suite.get(client, "/pokemon", nil)
+		suite.Require().NoError(err)
+	}
+
+	suite.Require().True(suite.server.AssertExpectations(suite.T()))
+	suite.Require().NoFileExists(suite.path)
+}
+
+func (suite *CassetteTestSuite) TestZeroMode() {
+	suite.server.Expect(http.MethodGet, "/pokemon/143").
+		Respond(http.StatusOK, "snorlax").
+		Once()
+
+	recorder := suite.client(snorlax.NewCassette(suite.path,
+		snorlax.CassetteRecord))
+	_, _, err := suite.get(recorder, "/pokemon/143", nil)
+	suite.Require().NoError(err)
+	suite.Require().True(suite.server.AssertExpectations(suite.T()))
+
+	saved, err := ioutil.ReadFile(suite.path)
+	suite.Require().NoError(err)
+
+	// A Cassette without a Mode replays, and leaves its file alone.
+	client := suite.client(&snorlax.Cassette{Path: suite.path})
+	_, body, err := suite.get(client, "/pokemon/143", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal("snorlax", body)
+
+	_, _, err = suite.get(client, "/pokemon/151", nil)
+	suite.Require().True(errors.Is(err, snorlax.ErrInteractionNotFound))
+	suite.Require().True(suite.server.AssertExpectations(suite.T()))
+
+	unchanged, err := ioutil.ReadFile(suite.path)
+	suite.Require().NoError(err)
+	suite.Require().Equal(saved, unchanged)
+}
+
+func TestCassetteTestSuite(t *testing.T) {
+	suite.Run(t, new(CassetteTestSuite))
+}
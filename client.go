@@ -6,8 +6,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -15,11 +15,20 @@ import (
 
 // Client defines a wrapper around an http.Client making it easier to send
 // requests to RESTful APIs.
+//
+// A Client is safe for concurrent use, including calling its setters while it
+// is sending requests. Each request uses the configuration the client had when
+// the request started, and RequestHooks only modify the request's own copy of
+// the client's headers.
 type Client interface {
 	// AddHeader appends a header value to the client to be sent in every
 	// request. To replace the current existing header use SetHeader.
 	AddHeader(key, value string) Client
 
+	// AddMiddleware appends Middleware which wraps every request the client
+	// sends. The first Middleware added is the outermost layer.
+	AddMiddleware(middleware ...Middleware) Client
+
 	// AddRequestHook appends a RequestHook to the list of hooks which are to be
 	// run just before the client sends a request. RequestHooks are executed in
 	// the order they are added.
@@ -29,12 +38,34 @@ type Client interface {
 	// multiple times.
 	AddRequestHooks(hooks ...RequestHook) Client
 
+	// AddResponseHook appends a ResponseHook to the list of hooks which are
+	// to be run just after the client receives a response. ResponseHooks are
+	// executed in the order they are added.
+	AddResponseHook(hook ResponseHook) Client
+
+	// AddResponseHooks is a convenience function which calls AddResponseHook
+	// multiple times.
+	AddResponseHooks(hooks ...ResponseHook) Client
+
+	// Clone returns a copy of the client which can be configured without
+	// affecting the original. The copy has its own copies of the RetryPolicy
+	// and RedactionPolicy, and writes to the same Logger at its own level. It
+	// shares the original's http.Client, and therefore its transport and
+	// connection pool, until it is configured with a different one.
+	Clone() Client
+
 	// Get performs a Get request. You can optionally configure the request
 	// using RequestHooks, or by configuring the client if you need to configure
 	// all requests.
 	Delete(ctx context.Context, target string, query url.Values, body io.Reader,
 		hooks ...RequestHook) (*Response, error)
 
+	// Do performs a request using an arbitrary HTTP method, such as PROPFIND
+	// or PURGE. You can optionally configure the request using RequestHooks,
+	// or by configuring the client if you need to configure all requests.
+	Do(ctx context.Context, method, target string, query url.Values,
+		body io.Reader, hooks ...RequestHook) (*Response, error)
+
 	// Get performs a Get request. You can optionally configure the request
 	// using RequestHooks, or by configuring the client if you need to configure
 	// all requests.
@@ -48,7 +79,7 @@ type Client interface {
 		hooks ...RequestHook) (*Response, error)
 
 	// Logger returns the client's internal logger.
-	Logger() *logrus.Logger
+	Logger() Logger
 
 	// Options performs a Options request. You can optionally configure the
 	// request using RequestHooks, or by configuring the client if you need to
@@ -56,6 +87,12 @@ type Client interface {
 	Options(ctx context.Context, target string, query url.Values,
 		hooks ...RequestHook) (*Response, error)
 
+	// Patch performs a Patch request. You can optionally configure the request
+	// using RequestHooks, or by configuring the client if you need to configure
+	// all requests.
+	Patch(ctx context.Context, target string, query url.Values, body io.Reader,
+		hooks ...RequestHook) (*Response, error)
+
 	// Post performs a Post request. You can optionally configure the request
 	// using RequestHooks, or by configuring the client if you need to configure
 	// all requests.
@@ -76,6 +113,19 @@ type Client interface {
 	// request URLs performed by the Client.
 	SetBaseURL(url string) Client
 
+	// SetCache sets the Cache which serves GET requests without contacting
+	// the upstream where possible. A nil Cache disables caching.
+	SetCache(cache *Cache) Client
+
+	// SetCassette sets the Cassette which records the client's requests, or
+	// replays previously recorded responses to them. A nil Cassette sends
+	// requests as usual.
+	SetCassette(cassette *Cassette) Client
+
+	// SetCircuitBreaker sets the CircuitBreaker which stops requests from
+	// being sent to failing upstreams. A nil CircuitBreaker disables it.
+	SetCircuitBreaker(breaker *CircuitBreaker) Client
+
 	// SetHeader sets a header value in the client to be sent in every request.
 	// This will overwrite any exiting headers present associated with the same
 	// key. To add headers to the key instead of replacing them use AddHeader.
@@ -86,19 +136,61 @@ type Client interface {
 	// internals.
 	SetHTTPClient(c *http.Client) Client
 
+	// SetHTTPErrors sets whether responses with a non-2XX status code are
+	// returned as an *HTTPError instead of a Response.
+	SetHTTPErrors(enabled bool) Client
+
+	// SetLogger replaces the Logger that the client writes its logs to. The
+	// client's log level is applied to the new Logger.
+	SetLogger(logger Logger) Client
+
 	// SetLogLevel sets the amount of logs the client will produce. The lower
 	// the level, the less logs will be written. By default, Snorlax uses the
 	// lowest possible level - PanicLevel.
-	SetLogLevel(level logrus.Level) Client
+	SetLogLevel(level Level) Client
+
+	// SetMetrics enables Prometheus metrics, registering them as configured
+	// by opts. A nil opts registers the default metrics with the default
+	// Prometheus registerer.
+	SetMetrics(opts *MetricsOptions) Client
+
+	// SetPathNormalizer sets the PathNormalizer which maps requests to the
+	// route templates used to label their metrics. Requests which aren't
+	// mapped to a template are labeled "other".
+	SetPathNormalizer(normalizer PathNormalizer) Client
 
 	// SetProxy sets the proxy URL in the clent's transport. If the URL fails
 	// to parse, nothing is set. This function fails silently. If you need more
 	// of a guarantee rather create your own http.Client with your proxy set and
 	// use SetHTTPClient.
 	SetProxy(url string) Client
+
+	// SetRateLimiter sets the RateLimiter which throttles requests before
+	// they are sent. A nil RateLimiter disables client-side rate limiting.
+	SetRateLimiter(limiter *RateLimiter) Client
+
+	// SetRedactionPolicy sets the policy deciding which header and query
+	// parameter values are masked in the client's logs. A nil policy uses
+	// DefaultRedactionPolicy.
+	SetRedactionPolicy(policy *RedactionPolicy) Client
+
+	// SetRetryPolicy sets the policy used to retry failed requests. A nil
+	// policy disables retries. Individual requests can override the policy
+	// using WithRetryPolicy.
+	SetRetryPolicy(policy *RetryPolicy) Client
+
+	// SetTimeout sets the time limit for requests, as http.Client's Timeout
+	// does. The timeout is set on a copy of the client's http.Client, which
+	// continues to share its transport.
+	SetTimeout(d time.Duration) Client
+
+	// With returns a Clone of the client configured with the Settings.
+	With(settings ...Setting) Client
 }
 
 // DefaultClient is a Snorlax client configured with all of the default options.
+// It is shared by the whole program, so derive a client using Clone or With
+// rather than configuring it directly.
 var DefaultClient = &client{
 	opts: Defaults(),
 }
@@ -112,6 +204,14 @@ func Delete(ctx context.Context, target string, query url.Values,
 		hooks...)
 }
 
+// Do performs a request with an arbitrary method using the DefaultClient. You
+// can optionally configure the request using RequestHooks. If you need to
+// configure every request then consider not using the DefaultClient.
+func Do(ctx context.Context, method, target string, query url.Values,
+	body io.Reader, hooks ...RequestHook) (*Response, error) {
+	return DefaultClient.call(ctx, method, target, query, body, hooks...)
+}
+
 // Get performs a get request using the DefaultClient. You can optionally
 // configure the request using RequestHooks. If you need to configure every
 // request then consider not using the DefaultClient.
@@ -137,6 +237,15 @@ func Options(ctx context.Context, target string, query url.Values,
 		opts...)
 }
 
+// Patch performs a patch request using the DefaultClient. You can optionally
+// configure the request using RequestHooks. If you need to configure every
+// request then consider not using the DefaultClient.
+func Patch(ctx context.Context, target string, query url.Values,
+	body io.Reader, opts ...RequestHook) (*Response, error) {
+	return DefaultClient.call(ctx, http.MethodPatch, target, query, body,
+		opts...)
+}
+
 // Post performs a post request using the DefaultClient. You can optionally
 // configure the request using RequestHooks. If you need to configure every
 // request then consider not using the DefaultClient.
@@ -146,7 +255,7 @@ func Post(ctx context.Context, target string, query url.Values,
 		opts...)
 }
 
-// Post performs a post request using the DefaultClient. You can optionally
+// Put performs a put request using the DefaultClient. You can optionally
 // configure the request using RequestHooks. If you need to configure every
 // request then consider not using the DefaultClient.
 func Put(ctx context.Context, target string, query url.Values,
@@ -155,64 +264,158 @@ func Put(ctx context.Context, target string, query url.Values,
 }
 
 // NewClient constructs a new Client configured with the provided ClientOptions.
+// If opts is nil then Defaults are used. The client keeps a reference to opts,
+// so they should not be modified after the client has been constructed; use
+// the client's setters instead.
 func NewClient(opts *ClientOptions) Client {
-	return &client{opts}
+	if opts == nil {
+		opts = Defaults()
+	}
+
+	return &client{opts: opts}
 }
 
+// client guards its options with mu, so that it can be configured while it is
+// sending requests. Requests work on a snapshot of the options taken when they
+// start, so configuration changes only affect requests made afterwards.
 type client struct {
-	opts *ClientOptions
+	mu      sync.RWMutex
+	opts    *ClientOptions
+	metrics *metrics
 }
 
 // ClientOptions contains the configuration options for a Snorlax client.
+// Prometheus metrics are only recorded if WithMetrics is set, in which case
+// Metrics optionally configures how they are registered.
 type ClientOptions struct {
-	BaseURL     string
-	WithMetrics bool
-
-	headers      http.Header
-	httpClient   *http.Client
-	logger       *logrus.Logger
-	logLevel     logrus.Level
-	proxyURL     *url.URL
-	requestHooks []RequestHook
+	BaseURL        string
+	Cache          *Cache
+	Cassette       *Cassette
+	CircuitBreaker *CircuitBreaker
+	Metrics        *MetricsOptions
+	PathNormalizer PathNormalizer
+	RateLimiter    *RateLimiter
+	Redaction      *RedactionPolicy
+	RetryPolicy    *RetryPolicy
+	WithHTTPErrors bool
+	WithMetrics    bool
+
+	headers       http.Header
+	httpClient    *http.Client
+	logger        Logger
+	logLevel      Level
+	middleware    []Middleware
+	proxyURL      *url.URL
+	requestHooks  []RequestHook
+	responseHooks []ResponseHook
 }
 
 // Defaults returns a set of default ClientOptions.
 func Defaults() *ClientOptions {
-	opts := ClientOptions{
-		BaseURL:     "",
-		WithMetrics: false,
+	// The client's own logger writes at every level, leaving the client's
+	// log level to decide which messages are written.
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetLevel(logrus.TraceLevel)
 
-		headers:      make(http.Header),
-		httpClient:   http.DefaultClient,
-		logger:       logrus.New(),
-		logLevel:     logrus.PanicLevel,
-		proxyURL:     nil,
-		requestHooks: make([]RequestHook, 0),
+	opts := ClientOptions{
+		BaseURL:        "",
+		Cache:          nil,
+		Cassette:       nil,
+		CircuitBreaker: nil,
+		Metrics:        nil,
+		PathNormalizer: nil,
+		RateLimiter:    nil,
+		Redaction:      DefaultRedactionPolicy(),
+		RetryPolicy:    nil,
+		WithHTTPErrors: false,
+		WithMetrics:    false,
+
+		headers:       make(http.Header),
+		httpClient:    http.DefaultClient,
+		logger:        NewLogrusLogger(logger),
+		logLevel:      PanicLevel,
+		middleware:    make([]Middleware, 0),
+		proxyURL:      nil,
+		requestHooks:  make([]RequestHook, 0),
+		responseHooks: make([]ResponseHook, 0),
 	}
 
 	opts.logger.SetLevel(opts.logLevel)
-	opts.logger.SetFormatter(&logrus.JSONFormatter{})
 
 	return &opts
 }
 
+// options returns a snapshot of the client's options. The headers and the
+// hook and Middleware slices are copied, so that neither requests nor setters
+// can modify the other's view of them.
+func (c *client) options() *ClientOptions {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	opts := *c.opts
+	opts.headers = c.opts.headers.Clone()
+	if opts.headers == nil {
+		opts.headers = make(http.Header)
+	}
+	opts.middleware = append([]Middleware(nil), c.opts.middleware...)
+	opts.requestHooks = append([]RequestHook(nil), c.opts.requestHooks...)
+	opts.responseHooks = append([]ResponseHook(nil),
+		c.opts.responseHooks...)
+
+	// httpClient is usually nil when the options weren't built using Defaults.
+	// This prevents panics by using the http.DefaultClient. In most cases, this
+	// will be sufficient. In cases where the caller wants more control over the
+	// client's configuration - SetHTTPClient can be used.
+	if opts.httpClient == nil {
+		opts.httpClient = http.DefaultClient
+	}
+
+	return &opts
+}
+
+// log returns an entry for writing to the client's Logger.
+func (c *client) log() logEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.opts.log()
+}
+
+// log returns an entry for writing to the options' Logger.
+func (opts *ClientOptions) log() logEntry {
+	redaction := opts.Redaction
+	if redaction == nil {
+		redaction = defaultRedaction
+	}
+
+	logger := opts.logger
+	if logger == nil {
+		logger = NopLogger()
+	}
+
+	return logEntry{logger: logger, redaction: redaction}
+}
+
 func (c *client) call(ctx context.Context, method, target string,
 	query url.Values, body io.Reader, hooks ...RequestHook) (*Response,
 	error) {
 
-	u := strings.Join([]string{c.opts.BaseURL, target}, "")
+	opts := c.options()
+
+	u := strings.Join([]string{opts.BaseURL, target}, "")
 	uri, err := url.Parse(u)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse url %s: %w", uri, err)
 	}
 
 	if uri.RawQuery != "" {
-		c.opts.logger.Warnln("query parameters should not be set on the " +
-			"path, they will be overridden")
+		opts.log().Warn("query parameters should not be set on the path, " +
+			"they will be overridden")
 	}
 	uri.RawQuery = query.Encode()
 
-	c.opts.logger.Tracef("uri parsed as %s", uri.String())
+	opts.log().WithURL("url", uri).Trace("uri parsed")
 
 	req, err := http.NewRequestWithContext(ctx, method, uri.String(), body)
 	if err != nil {
@@ -220,67 +423,255 @@ func (c *client) call(ctx context.Context, method, target string,
 	}
 
 	// Set the request headers with all the headers configured in the client.
-	if c.opts.headers == nil {
-		c.opts.headers = make(http.Header)
-	}
-	req.Header = c.opts.headers
+	// The snapshot's headers are a copy, so hooks can modify them freely.
+	req.Header = opts.headers
 
-	// httpClient is usually nil on the first request made by the client. This
-	// prevents panics by using the http.DefaultClient. In most cases, this will
-	// be sufficient. In cases where the caller wants more control over the
-	// client's configuration - SethttpClient can be used.
-	if c.opts.httpClient == nil {
-		c.opts.logger.Trace("internal client set to http.DefaultClient")
-		c.opts.httpClient = http.DefaultClient
-	}
-
-	c.opts.logger.Trace("running pre-request hooks")
+	opts.log().Trace("running pre-request hooks")
 	// We first apply the request options from the client, so that they can be
 	// optionally overridden by individual request options.
-	for _, hook := range append(c.opts.requestHooks, hooks...) {
+	for _, hook := range append(opts.requestHooks, hooks...) {
 		if err = hook(c, req); err != nil {
 			return nil, fmt.Errorf("failed to execute pre-request hook: %w",
 				err)
 		}
 	}
-	c.opts.logger.Trace("pre-request hooks complete")
+	opts.log().Trace("pre-request hooks complete")
 
 	for k, v := range req.Header {
-		c.opts.logger.WithField("key", k).WithField("value", v).
-			Trace("header set")
+		opts.log().WithHeader(k, v...).Trace("header set")
+	}
+
+	policy := opts.RetryPolicy
+	if p, ok := req.Context().Value(retryPolicyKey).(*RetryPolicy); ok {
+		policy = p
+	}
+
+	// Retried requests need to send their body more than once, so make sure
+	// it can be rewound before the first attempt consumes it.
+	if policy.enabled() {
+		if err = bufferBody(req); err != nil {
+			return nil, err
+		}
 	}
 
-	c.opts.logger.WithField("url", req.URL.String()).Trace("performing request")
+	m := c.collectors(opts)
+	defer m.trackInFlight()()
+
+	opts.log().WithURL("url", req.URL).Trace("performing request")
 	reqStart := time.Now()
-	res, err := c.opts.httpClient.Do(req)
+	var (
+		res         *http.Response
+		cacheStatus CacheStatus
+	)
+	if opts.Cache != nil {
+		res, cacheStatus, err = opts.Cache.do(req, func(r *http.Request) (*http.Response,
+			error) {
+			return c.do(opts, r, policy)
+		}, opts.log())
+	} else {
+		res, err = c.do(opts, req, policy)
+	}
 	if err != nil {
+		m.observeError(method, err)
 		return nil, fmt.Errorf("failed to perform http request: %w", err)
 	}
 
-	c.opts.logger.WithFields(logrus.Fields{
+	opts.log().WithURL("url", uri).WithFields(Fields{
 		"method":      method,
 		"latency":     time.Since(reqStart).Seconds(),
 		"status_code": res.StatusCode,
-		"url":         uri.String(),
 	}).Debug("request complete")
 
-	// TODO: Rethink how to provide the target path as a label effectively.
-	// clients sending requests to dynamic paths can overload prometheus.
-	if c.opts.WithMetrics {
-		latencyHist.WithLabelValues(method,
-			strconv.Itoa(res.StatusCode), req.URL.Path).Observe(
-			time.Since(reqStart).Seconds())
+	m.observeRequest(req, res, routeLabel(req, opts.PathNormalizer),
+		time.Since(reqStart).Seconds())
+
+	response := &Response{Response: *res, cacheStatus: cacheStatus,
+		redaction: opts.Redaction}
+
+	opts.log().Trace("running post-response hooks")
+	responseHooks, _ := req.Context().Value(responseHooksKey).([]ResponseHook)
+	for _, hook := range append(opts.responseHooks, responseHooks...) {
+		if err = hook(c, response); err != nil {
+			response.Body.Close()
+			return nil, fmt.Errorf("failed to execute post-response hook: %w",
+				err)
+		}
+	}
+	opts.log().Trace("post-response hooks complete")
+
+	if opts.WithHTTPErrors && !response.IsSuccess() {
+		return nil, newHTTPError(response)
+	}
+
+	return response, nil
+}
+
+// collectors returns the client's metrics, registering them on first use, or
+// nil if metrics are disabled in opts.
+func (c *client) collectors(opts *ClientOptions) *metrics {
+	if !opts.WithMetrics {
+		return nil
+	}
+
+	c.mu.RLock()
+	m := c.metrics
+	c.mu.RUnlock()
+	if m != nil {
+		return m
 	}
 
-	return &Response{*res}, nil
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.metrics == nil {
+		m, err := newMetrics(c.opts.Metrics)
+		if err != nil {
+			c.opts.log().WithError(err).
+				Warn("metrics may not be exported")
+		}
+		c.metrics = m
+	}
+
+	return c.metrics
+}
+
+// do sends the request, retrying it according to policy until it succeeds, the
+// attempts are exhausted or the request's context is done.
+func (c *client) do(opts *ClientOptions, req *http.Request,
+	policy *RetryPolicy) (*http.Response, error) {
+	// Client Middleware wraps request Middleware, so that it observes the
+	// request exactly as the caller configured it. Requests are signed last,
+	// so that the signature covers any changes made by Middleware, and are
+	// then recorded exactly as they are sent.
+	middleware, _ := req.Context().Value(middlewareKey).([]Middleware)
+	middleware = append(append(append([]Middleware(nil), opts.middleware...),
+		middleware...), sign)
+	if opts.Cassette != nil {
+		middleware = append(middleware, opts.Cassette.middleware)
+	}
+	doer := chain(opts.httpClient, middleware...)
+
+	if !policy.enabled() {
+		return c.send(opts, doer, req)
+	}
+
+	attemptReq := req
+	for attempt := 1; ; attempt++ {
+		res, err := c.send(opts, doer, attemptReq)
+		if attempt >= policy.MaxAttempts || !policy.retryable(res, err) {
+			return res, err
+		}
+
+		backoff, ok := policy.wait(req.Context(), attempt, res)
+		if !ok {
+			opts.log().WithURL("url", req.URL).
+				Debug("not retrying: requested delay exceeds limits")
+			return res, err
+		}
+
+		log := opts.log().WithURL("url", req.URL).WithFields(Fields{
+			"attempt":      attempt,
+			"max_attempts": policy.MaxAttempts,
+			"backoff":      backoff.Seconds(),
+		})
+		if err != nil {
+			log = log.WithError(err)
+		} else {
+			log = log.WithField("status_code", res.StatusCode)
+			discard(res)
+		}
+		log.Debug("retrying request")
+
+		c.collectors(opts).observeRetry(req.Method)
+
+		if err = sleep(req.Context(), backoff); err != nil {
+			return nil, err
+		}
+
+		if attemptReq, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// send checks the client's CircuitBreaker, waits for the client's RateLimiter
+// to allow the request, and sends it.
+func (c *client) send(opts *ClientOptions, doer Doer,
+	req *http.Request) (*http.Response, error) {
+	breaker := opts.CircuitBreaker
+	if breaker == nil {
+		return c.throttle(opts, doer, req)
+	}
+
+	key := breaker.key(req)
+	t, err := breaker.allow(key, time.Now())
+	c.circuitTransition(opts, t)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.throttle(opts, doer, req)
+	c.circuitTransition(opts, breaker.record(key, res, err, time.Now()))
+
+	return res, err
+}
+
+// circuitTransition logs and records a change in the state of a circuit.
+func (c *client) circuitTransition(opts *ClientOptions, t *transition) {
+	if t == nil {
+		return
+	}
+
+	opts.log().WithFields(Fields{
+		"key":  t.key,
+		"from": t.from.String(),
+		"to":   t.to.String(),
+	}).Warn("circuit breaker state changed")
+
+	c.collectors(opts).setCircuitState(t.key, t.to)
+}
+
+// throttle waits for the client's RateLimiter to allow the request, and sends
+// it.
+func (c *client) throttle(opts *ClientOptions, doer Doer,
+	req *http.Request) (*http.Response, error) {
+	if opts.RateLimiter != nil {
+		wait, err := opts.RateLimiter.Wait(req.Context(), req)
+		if err != nil {
+			return nil, err
+		}
+
+		if wait > 0 {
+			opts.log().WithURL("url", req.URL).WithField("wait", wait.Seconds()).
+				Debug("request delayed by rate limiter")
+		}
+
+		c.collectors(opts).observeRateLimitWait(wait.Seconds())
+	}
+
+	return doer.Do(req)
 }
 
 // AddHeader appends a header value to the client to be sent in every request.
 // To replace the current existing header use SetHeader.
 func (c *client) AddHeader(key, value string) Client {
-	c.opts.logger.WithFields(logrus.Fields{"key": key, "value": value}).
-		Trace("setting request header")
+	c.mu.Lock()
+	if c.opts.headers == nil {
+		c.opts.headers = make(http.Header)
+	}
 	c.opts.headers.Add(key, value)
+	c.mu.Unlock()
+
+	c.log().WithHeader(key, value).Trace("request header added")
+	return c
+}
+
+// AddMiddleware satisfies the Client interface.
+func (c *client) AddMiddleware(middleware ...Middleware) Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.opts.middleware = append(c.opts.middleware, middleware...)
 	return c
 }
 
@@ -288,9 +679,8 @@ func (c *client) AddHeader(key, value string) Client {
 // just before the client sends a request. RequestHooks are executed in the
 // order they are added.
 func (c *client) AddRequestHook(hook RequestHook) Client {
-	if c.opts.requestHooks == nil {
-		c.opts.requestHooks = make([]RequestHook, 0)
-	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
 	c.opts.requestHooks = append(c.opts.requestHooks, hook)
 	return c
@@ -306,12 +696,52 @@ func (c *client) AddRequestHooks(hooks ...RequestHook) Client {
 	return c
 }
 
+// AddResponseHook appends a ResponseHook to the list of hooks which are to be
+// run just after the client receives a response. ResponseHooks are executed in
+// the order they are added.
+func (c *client) AddResponseHook(hook ResponseHook) Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.opts.responseHooks = append(c.opts.responseHooks, hook)
+	return c
+}
+
+// AddResponseHooks is a convenience function which calls AddResponseHook
+// multiple times.
+func (c *client) AddResponseHooks(hooks ...ResponseHook) Client {
+	for _, hook := range hooks {
+		c.AddResponseHook(hook)
+	}
+
+	return c
+}
+
+// Clone satisfies the Client interface.
+func (c *client) Clone() Client {
+	opts := c.options()
+	opts.logger = cloneLogger(opts.logger)
+	opts.RetryPolicy = opts.RetryPolicy.clone()
+	opts.Redaction = opts.Redaction.clone()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return &client{opts: opts, metrics: c.metrics}
+}
+
 // Delete satisfies the Client interface.
 func (c *client) Delete(ctx context.Context, target string, query url.Values,
 	body io.Reader, hooks ...RequestHook) (*Response, error) {
 	return c.call(ctx, http.MethodDelete, target, query, body, hooks...)
 }
 
+// Do satisfies the Client interface.
+func (c *client) Do(ctx context.Context, method, target string,
+	query url.Values, body io.Reader, hooks ...RequestHook) (*Response, error) {
+	return c.call(ctx, method, target, query, body, hooks...)
+}
+
 // Get satisfies the Client interface.
 func (c *client) Get(ctx context.Context, target string, query url.Values,
 	opts ...RequestHook) (*Response, error) {
@@ -325,7 +755,14 @@ func (c *client) Head(ctx context.Context, target string, query url.Values,
 }
 
 // Logger satisfies the Client interface.
-func (c *client) Logger() *logrus.Logger {
+func (c *client) Logger() Logger {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.opts.logger == nil {
+		return NopLogger()
+	}
+
 	return c.opts.logger
 }
 
@@ -335,6 +772,12 @@ func (c *client) Options(ctx context.Context, target string, query url.Values,
 	return c.call(ctx, http.MethodOptions, target, query, nil, opts...)
 }
 
+// Patch satisfies the Client interface.
+func (c *client) Patch(ctx context.Context, target string, query url.Values,
+	body io.Reader, opts ...RequestHook) (*Response, error) {
+	return c.call(ctx, http.MethodPatch, target, query, body, opts...)
+}
+
 // Post satisfies the Client interface.
 func (c *client) Post(ctx context.Context, target string, query url.Values,
 	body io.Reader, opts ...RequestHook) (*Response, error) {
@@ -349,30 +792,57 @@ func (c *client) Put(ctx context.Context, target string, query url.Values,
 
 // RemoveProxy removes the currently set proxy.
 func (c *client) RemoveProxy() Client {
-	t, ok := c.opts.httpClient.Transport.(*http.Transport)
+	c.mu.Lock()
+	hc, ok := withTransport(c.opts.httpClient, func(t *http.Transport) {
+		t.Proxy = nil
+	})
+	if ok {
+		c.opts.httpClient = hc
+		c.opts.proxyURL = nil
+	}
+	c.mu.Unlock()
+
 	if !ok {
-		c.opts.logger.Warn("proxy not removed: client transport failed " +
+		c.log().Warn("proxy not removed: client transport failed " +
 			"assertion")
 		return c
 	}
 
-	c.opts.proxyURL = nil
-	t.Proxy = nil
-	c.opts.logger.Trace("proxy removed")
-
+	c.log().Trace("proxy removed")
 	return c
 }
 
 // SetBaseURL sets the url that is prepended to all request URLs.
 func (c *client) SetBaseURL(u string) Client {
 	if _, err := url.Parse(u); err != nil {
-		c.opts.logger.WithField("url", u).
+		c.log().WithRawURL("url", u).
 			Warn("base url not set: failed to parse")
 		return c
 	}
 
-	c.opts.BaseURL = u
-	c.opts.logger.WithField("url", u).Trace("base url set")
+	c.update(func(opts *ClientOptions) { opts.BaseURL = u })
+	c.log().WithRawURL("url", u).Trace("base url set")
+	return c
+}
+
+// SetCache satisfies the Client interface.
+func (c *client) SetCache(cache *Cache) Client {
+	c.update(func(opts *ClientOptions) { opts.Cache = cache })
+	c.log().Trace("cache set")
+	return c
+}
+
+// SetCassette satisfies the Client interface.
+func (c *client) SetCassette(cassette *Cassette) Client {
+	c.update(func(opts *ClientOptions) { opts.Cassette = cassette })
+	c.log().Trace("cassette set")
+	return c
+}
+
+// SetCircuitBreaker satisfies the Client interface.
+func (c *client) SetCircuitBreaker(breaker *CircuitBreaker) Client {
+	c.update(func(opts *ClientOptions) { opts.CircuitBreaker = breaker })
+	c.log().Trace("circuit breaker set")
 	return c
 }
 
@@ -380,25 +850,54 @@ func (c *client) SetBaseURL(u string) Client {
 // will overwrite any exiting headers present associated with the same key. To
 // add headers to the key instead of replacing them use AddHeader.
 func (c *client) SetHeader(key, value string) Client {
-	c.opts.headers.Set(key, value)
-	c.opts.logger.WithField("key", key).WithField("value", value).
-		Trace("header set")
+	c.update(func(opts *ClientOptions) {
+		if opts.headers == nil {
+			opts.headers = make(http.Header)
+		}
+		opts.headers.Set(key, value)
+	})
+	c.log().WithHeader(key, value).Trace("header set")
 	return c
 }
 
 // SetHTTPClient sets the internal http.client that Snorlax uses to perform
 // requests. Use this if you want to configure client internals like timeouts.
 func (c *client) SetHTTPClient(client *http.Client) Client {
-	c.opts.httpClient = client
-	c.opts.logger.Trace("http client set")
+	c.update(func(opts *ClientOptions) { opts.httpClient = client })
+	c.log().Trace("http client set")
+	return c
+}
+
+// SetHTTPErrors satisfies the Client interface.
+func (c *client) SetHTTPErrors(enabled bool) Client {
+	c.update(func(opts *ClientOptions) { opts.WithHTTPErrors = enabled })
+	c.log().WithField("enabled", enabled).Trace("http errors set")
+	return c
+}
+
+// SetLogger satisfies the Client interface.
+func (c *client) SetLogger(logger Logger) Client {
+	if logger == nil {
+		logger = NopLogger()
+	}
+
+	c.update(func(opts *ClientOptions) {
+		logger.SetLevel(opts.logLevel)
+		opts.logger = logger
+	})
+	c.log().Trace("logger set")
 	return c
 }
 
 // SetLogLevel satisfies the Client interface.
-func (c *client) SetLogLevel(level logrus.Level) Client {
-	c.opts.logger.SetLevel(level)
-	c.opts.logLevel = level
-	c.opts.logger.WithField("level", level.String()).Trace("log level set")
+func (c *client) SetLogLevel(level Level) Client {
+	c.update(func(opts *ClientOptions) {
+		if opts.logger != nil {
+			opts.logger.SetLevel(level)
+		}
+		opts.logLevel = level
+	})
+	c.log().WithField("level", level.String()).Trace("log level set")
 	return c
 }
 
@@ -407,30 +906,140 @@ func (c *client) SetLogLevel(level logrus.Level) Client {
 // replace any existing RequestHooks that have been added. To add RequestHooks
 // without replacing other hooks use AddRequestHook(s).
 func (c *client) SetRequestHooks(hooks []RequestHook) Client {
-	c.opts.requestHooks = hooks
+	c.update(func(opts *ClientOptions) {
+		opts.requestHooks = append([]RequestHook(nil), hooks...)
+	})
+	return c
+}
+
+// SetRateLimiter satisfies the Client interface.
+func (c *client) SetRateLimiter(limiter *RateLimiter) Client {
+	c.update(func(opts *ClientOptions) { opts.RateLimiter = limiter })
+	c.log().Trace("rate limiter set")
+	return c
+}
+
+// SetRedactionPolicy satisfies the Client interface.
+func (c *client) SetRedactionPolicy(policy *RedactionPolicy) Client {
+	c.update(func(opts *ClientOptions) { opts.Redaction = policy })
+	c.log().Trace("redaction policy set")
+	return c
+}
+
+// SetRetryPolicy satisfies the Client interface.
+func (c *client) SetRetryPolicy(policy *RetryPolicy) Client {
+	c.update(func(opts *ClientOptions) { opts.RetryPolicy = policy })
+	c.log().Trace("retry policy set")
+	return c
+}
+
+// SetTimeout satisfies the Client interface.
+func (c *client) SetTimeout(d time.Duration) Client {
+	c.update(func(opts *ClientOptions) {
+		hc := *http.DefaultClient
+		if opts.httpClient != nil {
+			hc = *opts.httpClient
+		}
+
+		hc.Timeout = d
+		opts.httpClient = &hc
+	})
+	c.log().WithField("timeout", d.String()).Trace("timeout set")
+	return c
+}
+
+// SetMetrics satisfies the Client interface.
+func (c *client) SetMetrics(metricsOpts *MetricsOptions) Client {
+	c.mu.Lock()
+	c.opts.Metrics = metricsOpts
+	c.opts.WithMetrics = true
+	c.metrics = nil
+	c.mu.Unlock()
+
+	c.log().Trace("metrics set")
+	return c
+}
+
+// SetPathNormalizer satisfies the Client interface.
+func (c *client) SetPathNormalizer(normalizer PathNormalizer) Client {
+	c.update(func(opts *ClientOptions) { opts.PathNormalizer = normalizer })
+	c.log().Trace("path normalizer set")
 	return c
 }
 
 // SetProxy sets the proxy URL for the Snorlax client. If the provided URL fails
 // to be parsed then nothing will be set.
 func (c *client) SetProxy(u string) Client {
-	t, ok := c.opts.httpClient.Transport.(*http.Transport)
-	if !ok {
-		c.opts.logger.Warn("proxy not set: client transport failed " +
-			"assertion")
-		return c
-	}
-
 	proxyURL, err := url.Parse(u)
 	if err != nil {
-		c.opts.logger.WithField("url", u).
+		c.log().WithRawURL("url", u).
 			Warn("proxy url not set: failed to parse")
 		return c
 	}
 
-	c.opts.proxyURL = proxyURL
-	t.Proxy = http.ProxyURL(proxyURL)
-	c.opts.logger.WithField("url", u).Trace("proxy url set")
+	c.mu.Lock()
+	hc, ok := withTransport(c.opts.httpClient, func(t *http.Transport) {
+		t.Proxy = http.ProxyURL(proxyURL)
+	})
+	if ok {
+		c.opts.httpClient = hc
+		c.opts.proxyURL = proxyURL
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		c.log().Warn("proxy not set: client transport failed " +
+			"assertion")
+		return c
+	}
 
+	c.log().WithRawURL("url", u).Trace("proxy url set")
 	return c
 }
+
+// With satisfies the Client interface.
+func (c *client) With(settings ...Setting) Client {
+	clone := c.Clone()
+	for _, setting := range settings {
+		setting(clone)
+	}
+
+	return clone
+}
+
+// update applies fn to the client's options while holding the client's lock.
+// fn must not call any of the client's methods.
+func (c *client) update(fn func(opts *ClientOptions)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fn(c.opts)
+}
+
+// withTransport returns a copy of hc with a copy of its transport modified by
+// fn, so that the change doesn't affect other users of hc or its transport. A
+// nil client or transport is treated as the http.DefaultClient and
+// http.DefaultTransport.
+func withTransport(hc *http.Client,
+	fn func(t *http.Transport)) (*http.Client, bool) {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+
+	rt := hc.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, false
+	}
+
+	t = t.Clone()
+	fn(t)
+
+	clone := *hc
+	clone.Transport = t
+	return &clone, true
+}
@@ -1,12 +1,21 @@
 package snorlax_test
 
 import (
+	"bytes"
 	"context"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/nickcorin/snorlax"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
@@ -24,7 +33,8 @@ func (suite *ClientTestSuite) SetupSuite() {
 	}
 
 	suite.server = httptest.NewServer(http.HandlerFunc(h))
-	suite.client = snorlax.DefaultClient.SetBaseURL(suite.server.URL)
+	suite.client = snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL)
 }
 
 func (suite *ClientTestSuite) TearDownSuite() {
@@ -38,6 +48,20 @@ func (suite *ClientTestSuite) TestClient_Delete() {
 	require.Equal(suite.T(), http.StatusOK, res.StatusCode)
 }
 
+func (suite *ClientTestSuite) TestClient_Do() {
+	res, err := suite.client.Do(context.TODO(), "PURGE", "/purge", nil, nil)
+	require.NoError(suite.T(), err)
+	require.NotNil(suite.T(), res)
+	require.Equal(suite.T(), http.StatusOK, res.StatusCode)
+	require.Equal(suite.T(), "PURGE", res.Request.Method)
+}
+
+func (suite *ClientTestSuite) TestClient_Do_InvalidMethod() {
+	res, err := suite.client.Do(context.TODO(), "BAD METHOD", "/", nil, nil)
+	require.Error(suite.T(), err)
+	require.Nil(suite.T(), res)
+}
+
 func (suite *ClientTestSuite) TestClient_Get() {
 	res, err := suite.client.Get(context.TODO(), "/get", nil)
 	require.NoError(suite.T(), err)
@@ -45,6 +69,14 @@ func (suite *ClientTestSuite) TestClient_Get() {
 	require.Equal(suite.T(), http.StatusOK, res.StatusCode)
 }
 
+func (suite *ClientTestSuite) TestClient_Patch() {
+	res, err := suite.client.Patch(context.TODO(), "/patch", nil, nil)
+	require.NoError(suite.T(), err)
+	require.NotNil(suite.T(), res)
+	require.Equal(suite.T(), http.StatusOK, res.StatusCode)
+	require.Equal(suite.T(), http.MethodPatch, res.Request.Method)
+}
+
 func (suite *ClientTestSuite) TestClient_Post() {
 	res, err := suite.client.Post(context.TODO(), "/post", nil, nil)
 	require.NoError(suite.T(), err)
@@ -59,6 +91,207 @@ func (suite *ClientTestSuite) TestClient_Put() {
 	require.Equal(suite.T(), http.StatusOK, res.StatusCode)
 }
 
+func TestClient_RequestHeadersNotShared(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(headerHandler))
+	defer server.Close()
+
+	c := snorlax.NewClient(nil).SetBaseURL(server.URL).
+		SetHeader("X-Client", "snorlax")
+
+	res, err := c.Get(context.TODO(), "/", nil,
+		snorlax.WithHeader("X-Request", "first"))
+	require.NoError(t, err)
+	require.Equal(t, "snorlax first", readBody(t, res))
+
+	res, err = c.Get(context.TODO(), "/", nil)
+	require.NoError(t, err)
+	require.Equal(t, "snorlax ", readBody(t, res))
+}
+
+func TestClient_Concurrent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(headerHandler))
+	defer server.Close()
+
+	c := snorlax.NewClient(nil).SetBaseURL(server.URL).
+		SetHeader("X-Client", "snorlax")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			value := strconv.Itoa(i)
+			res, err := c.Get(context.TODO(), "/", nil,
+				snorlax.WithHeader("X-Request", value))
+			if assert.NoError(t, err) {
+				assert.Equal(t, "snorlax "+value, readBody(t, res))
+			}
+		}(i)
+	}
+
+	// Reconfigure the client while it is sending requests.
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			c.SetBaseURL(server.URL).
+				SetRetryPolicy(snorlax.DefaultRetryPolicy()).
+				SetLogLevel(snorlax.TraceLevel).
+				SetLogger(snorlax.NopLogger()).
+				AddHeader("X-Other", "value").
+				AddRequestHook(func(snorlax.Client, *http.Request) error {
+					return nil
+				}).
+				SetMetrics(&snorlax.MetricsOptions{
+					Registerer: prometheus.NewRegistry(),
+				})
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestClient_Clone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(headerHandler))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetLevel(logrus.TraceLevel)
+
+	original := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(server.URL).
+		SetHeader("X-Client", "snorlax").
+		SetLogger(snorlax.NewLogrusLogger(logger))
+
+	clone := original.Clone().
+		SetHeader("X-Client", "munchlax").
+		AddRequestHook(snorlax.WithHeader("X-Request", "clone")).
+		SetLogLevel(snorlax.TraceLevel)
+
+	res, err := clone.Get(context.TODO(), "/", nil)
+	require.NoError(t, err)
+	require.Equal(t, "munchlax clone", readBody(t, res))
+	require.Contains(t, buf.String(), "performing request")
+
+	// The clone logs to the same output, but at its own level.
+	buf.Reset()
+	res, err = original.Get(context.TODO(), "/", nil)
+	require.NoError(t, err)
+	require.Equal(t, "snorlax ", readBody(t, res))
+	require.Equal(t, logrus.TraceLevel, logger.GetLevel())
+	require.Empty(t, buf.String())
+}
+
+func TestClient_ClonePolicies(t *testing.T) {
+	var mu sync.Mutex
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			attempts++
+			mu.Unlock()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetLevel(logrus.TraceLevel)
+
+	policy := &snorlax.RetryPolicy{
+		MaxAttempts:          3,
+		InitialBackoff:       time.Millisecond,
+		RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+	}
+	redaction := &snorlax.RedactionPolicy{QueryParams: []string{"token"}}
+
+	opts := snorlax.Defaults()
+	opts.RetryPolicy = policy
+	opts.Redaction = redaction
+
+	original := snorlax.NewClient(opts).
+		SetBaseURL(server.URL).
+		SetLogger(snorlax.NewLogrusLogger(logger)).
+		SetLogLevel(snorlax.TraceLevel)
+	clone := original.Clone()
+
+	// Changing the policies afterwards doesn't affect the clone.
+	policy.MaxAttempts = 1
+	redaction.QueryParams[0] = "name"
+
+	query := url.Values{"token": {"s3cr3t"}, "name": {"snorlax"}}
+	_, err := clone.Get(context.TODO(), "/", query)
+	require.NoError(t, err)
+	require.Equal(t, 3, attempts)
+	require.NotContains(t, buf.String(), "s3cr3t")
+	require.Contains(t, buf.String(), "snorlax")
+
+	buf.Reset()
+	attempts = 0
+	_, err = original.Get(context.TODO(), "/", query)
+	require.NoError(t, err)
+	require.Equal(t, 1, attempts)
+	require.Contains(t, buf.String(), "s3cr3t")
+	require.NotContains(t, buf.String(), "snorlax")
+}
+
+func TestClient_With(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				time.Sleep(100 * time.Millisecond)
+			}
+			headerHandler(w, r)
+		}))
+	defer server.Close()
+
+	original := snorlax.NewClient(snorlax.Defaults()).
+		SetHeader("X-Client", "snorlax")
+
+	derived := original.With(
+		snorlax.UseBaseURL(server.URL),
+		snorlax.UseHeader("X-Client", "munchlax"),
+		snorlax.UseTimeout(10*time.Millisecond),
+		// Callers can write their own Settings using the Client's setters.
+		func(c snorlax.Client) {
+			c.AddRequestHook(snorlax.WithHeader("X-Request", "derived"))
+		},
+	)
+
+	res, err := derived.Get(context.TODO(), "/", nil)
+	require.NoError(t, err)
+	require.Equal(t, "munchlax derived", readBody(t, res))
+
+	_, err = derived.Get(context.TODO(), "/slow", nil)
+	require.Error(t, err)
+
+	res, err = original.Get(context.TODO(), server.URL+"/slow", nil)
+	require.NoError(t, err)
+	require.Equal(t, "snorlax ", readBody(t, res))
+}
+
+// headerHandler writes the X-Client and X-Request request headers.
+func headerHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(r.Header.Get("X-Client") + " " +
+		r.Header.Get("X-Request")))
+}
+
+// readBody reads and closes the response body.
+func readBody(t *testing.T, res *snorlax.Response) string {
+	t.Helper()
+
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	assert.NoError(t, err)
+	return string(b)
+}
+
 func TestClientTestSuite(t *testing.T) {
 	suite.Run(t, new(ClientTestSuite))
 }
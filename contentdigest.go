@@ -0,0 +1,64 @@
+package snorlax
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// ContentDigest returns a Signer which sets the Content-Digest header of the
+// request to the digest of its body, as described in RFC 9530. The algorithm
+// is either sha-256 or sha-512. The body is not consumed.
+func ContentDigest(algorithm string) Signer {
+	return SignerFunc(func(req *http.Request) error {
+		algorithm := strings.ToLower(algorithm)
+		digest, err := bodyDigest(req, algorithm)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Content-Digest", algorithm+"=:"+digest+":")
+		return nil
+	})
+}
+
+// DigestHeader returns a Signer which sets the legacy Digest header of the
+// request to the digest of its body, as described in RFC 3230. The algorithm
+// is either SHA-256 or SHA-512. The body is not consumed.
+func DigestHeader(algorithm string) Signer {
+	return SignerFunc(func(req *http.Request) error {
+		algorithm := strings.ToUpper(algorithm)
+		digest, err := bodyDigest(req, algorithm)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Digest", algorithm+"="+digest)
+		return nil
+	})
+}
+
+// bodyDigest returns the base64 encoded digest of the request's body.
+func bodyDigest(req *http.Request, algorithm string) (string, error) {
+	var h hash.Hash
+	switch strings.ToLower(algorithm) {
+	case "sha-256":
+		h = sha256.New()
+	case "sha-512":
+		h = sha512.New()
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm %s", algorithm)
+	}
+
+	body, err := peekBody(req)
+	if err != nil {
+		return "", err
+	}
+
+	h.Write(body)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,330 @@
+package snorlax
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DigestAuth authenticates requests using HTTP Digest authentication, as
+// described in RFC 7616. The MD5, MD5-sess, SHA-256 and SHA-256-sess
+// algorithms are supported with qop=auth, as well as servers which don't
+// support qop.
+//
+// The challenge from the server's first 401 Unauthorized response is answered
+// by retrying the request, and then reused to authorize later requests up
+// front, counting the uses of its nonce. A DigestAuth is safe for concurrent
+// use.
+type DigestAuth struct {
+	username string
+	password string
+
+	mu        sync.Mutex
+	challenge *digestChallenge
+	count     uint32
+}
+
+// digestChallenge holds the parameters of a WWW-Authenticate Digest challenge.
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+	stale     bool
+}
+
+// NewDigestAuth returns a DigestAuth for the username and password.
+func NewDigestAuth(username, password string) *DigestAuth {
+	return &DigestAuth{username: username, password: password}
+}
+
+// WithDigestAuth authenticates requests using d. Add it to a client using
+// AddRequestHook to share challenges and nonce counts between requests. The
+// body of a request which is challenged must be able to be sent again, which
+// is the case for bodies such as a bytes.Buffer or strings.Reader.
+func WithDigestAuth(d *DigestAuth) RequestHook {
+	return WithMiddleware(d.middleware)
+}
+
+// middleware authorizes requests using the latest challenge, and answers new
+// challenges by retrying the request once.
+func (d *DigestAuth) middleware(next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		first := req
+		auth, nonce, ok := d.authorize(req, nil)
+		if ok {
+			first = req.Clone(req.Context())
+			first.Header.Set("Authorization", auth)
+		} else {
+			// Without a challenge the first attempt will be rejected, so keep
+			// its body to send it again.
+			if err := bufferBody(req); err != nil {
+				return nil, err
+			}
+		}
+
+		res, err := next.Do(first)
+		if err != nil || res.StatusCode != http.StatusUnauthorized {
+			return res, err
+		}
+
+		c := parseDigestChallenge(res.Header.Values("WWW-Authenticate"))
+		if c == nil {
+			return res, nil
+		}
+
+		// A request which was authorized with a nonce that is still valid was
+		// rejected because the credentials are wrong, so trying again won't
+		// help.
+		if ok && nonce == c.nonce && !c.stale {
+			return res, nil
+		}
+
+		auth, _, ok = d.authorize(req, c)
+		if !ok {
+			return res, nil
+		}
+
+		if !rewindable(req) {
+			return res, nil
+		}
+
+		retry, err := rewind(req)
+		if err != nil {
+			return res, nil
+		}
+		retry.Header.Set("Authorization", auth)
+		discard(res)
+
+		return next.Do(retry)
+	})
+}
+
+// authorize returns the Authorization header for the request, answering the
+// challenge c, and the nonce it uses. If c is nil then the latest challenge is
+// used. It returns false if there is no challenge, or the challenge can't be
+// answered.
+func (d *DigestAuth) authorize(req *http.Request, c *digestChallenge) (string,
+	string, bool) {
+	d.mu.Lock()
+	if c != nil {
+		d.challenge, d.count = c, 0
+	}
+	c = d.challenge
+	if c == nil {
+		d.mu.Unlock()
+		return "", "", false
+	}
+	d.count++
+	count := d.count
+	d.mu.Unlock()
+
+	h, sess, ok := digestHash(c.algorithm)
+	if !ok {
+		return "", "", false
+	}
+
+	qop := ""
+	if c.qop != "" {
+		for _, q := range strings.Split(c.qop, ",") {
+			if strings.TrimSpace(q) == "auth" {
+				qop = "auth"
+			}
+		}
+
+		// Only auth-int is offered, which would require hashing the body.
+		if qop == "" {
+			return "", "", false
+		}
+	}
+
+	cnonce, err := randomHex(16)
+	if err != nil {
+		return "", "", false
+	}
+
+	nc := fmt.Sprintf("%08x", count)
+	uri := req.URL.RequestURI()
+
+	ha1 := digestHex(h, d.username+":"+c.realm+":"+d.password)
+	if sess {
+		ha1 = digestHex(h, ha1+":"+c.nonce+":"+cnonce)
+	}
+	ha2 := digestHex(h, req.Method+":"+uri)
+
+	var response string
+	if qop == "" {
+		response = digestHex(h, ha1+":"+c.nonce+":"+ha2)
+	} else {
+		response = digestHex(h, strings.Join([]string{ha1, c.nonce, nc,
+			cnonce, qop, ha2}, ":"))
+	}
+
+	params := []string{
+		fmt.Sprintf("username=%s", quote(d.username)),
+		fmt.Sprintf("realm=%s", quote(c.realm)),
+		fmt.Sprintf("nonce=%s", quote(c.nonce)),
+		fmt.Sprintf("uri=%s", quote(uri)),
+	}
+	if c.algorithm != "" {
+		params = append(params, fmt.Sprintf("algorithm=%s", c.algorithm))
+	}
+	params = append(params, fmt.Sprintf("response=%s", quote(response)))
+	if c.opaque != "" {
+		params = append(params, fmt.Sprintf("opaque=%s", quote(c.opaque)))
+	}
+	if qop != "" {
+		params = append(params, "qop="+qop, "nc="+nc,
+			fmt.Sprintf("cnonce=%s", quote(cnonce)))
+	}
+
+	return "Digest " + strings.Join(params, ", "), c.nonce, true
+}
+
+// digestHash returns the hash function for the algorithm, and whether it is a
+// session variant.
+func digestHash(algorithm string) (func() hash.Hash, bool, bool) {
+	switch strings.ToUpper(algorithm) {
+	case "", "MD5":
+		return md5.New, false, true
+	case "MD5-SESS":
+		return md5.New, true, true
+	case "SHA-256":
+		return sha256.New, false, true
+	case "SHA-256-SESS":
+		return sha256.New, true, true
+	default:
+		return nil, false, false
+	}
+}
+
+// digestHex returns the hex encoded hash of s.
+func digestHex(h func() hash.Hash, s string) string {
+	d := h()
+	d.Write([]byte(s))
+	return hex.EncodeToString(d.Sum(nil))
+}
+
+// randomHex returns n random bytes, hex encoded.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// quote returns s as a quoted string.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// parseDigestChallenge returns the strongest Digest challenge in the
+// WWW-Authenticate header values, or nil if there is none which is supported.
+func parseDigestChallenge(values []string) *digestChallenge {
+	var best *digestChallenge
+	for _, v := range values {
+		for _, params := range digestChallenges(v) {
+			c := &digestChallenge{
+				realm:     params["realm"],
+				nonce:     params["nonce"],
+				opaque:    params["opaque"],
+				algorithm: params["algorithm"],
+				qop:       params["qop"],
+				stale:     strings.EqualFold(params["stale"], "true"),
+			}
+
+			if _, _, ok := digestHash(c.algorithm); !ok || c.nonce == "" {
+				continue
+			}
+
+			if best == nil || strings.HasPrefix(
+				strings.ToUpper(c.algorithm), "SHA-256") {
+				best = c
+			}
+		}
+	}
+
+	return best
+}
+
+// digestChallenges returns the parameters of each Digest challenge in a
+// WWW-Authenticate header value, which may contain several challenges.
+func digestChallenges(v string) []map[string]string {
+	var challenges []map[string]string
+	var params map[string]string
+
+	for v = strings.TrimSpace(v); v != ""; {
+		token, rest := readToken(v)
+		rest = strings.TrimLeft(rest, " \t")
+
+		if token == "" {
+			// Skip anything we can't parse.
+			v = strings.TrimLeft(rest[1:], " \t,")
+			continue
+		}
+
+		if !strings.HasPrefix(rest, "=") {
+			// A token which isn't followed by = starts a new challenge.
+			params = nil
+			if strings.EqualFold(token, "Digest") {
+				params = make(map[string]string)
+				challenges = append(challenges, params)
+			}
+			v = strings.TrimLeft(rest, " \t,")
+			continue
+		}
+
+		rest = strings.TrimLeft(rest[1:], " \t")
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			value, rest = readQuoted(rest)
+		} else {
+			value, rest = readToken(rest)
+		}
+
+		if params != nil {
+			params[strings.ToLower(token)] = value
+		}
+		v = strings.TrimLeft(rest, " \t,")
+	}
+
+	return challenges
+}
+
+// readToken splits s after its leading token.
+func readToken(s string) (string, string) {
+	i := strings.IndexAny(s, " \t,=\"")
+	if i < 0 {
+		return s, ""
+	}
+
+	return s[:i], s[i:]
+}
+
+// readQuoted splits s after its leading quoted string, returning the unquoted
+// string.
+func readQuoted(s string) (string, string) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return b.String(), s[i+1:]
+		case '\\':
+			if i+1 < len(s) {
+				i++
+			}
+		}
+		b.WriteByte(s[i])
+	}
+
+	return b.String(), ""
+}
@@ -0,0 +1,225 @@
+package snorlax_test
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/stretchr/testify/suite"
+)
+
+type DigestAuthTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+
+	mu        sync.Mutex
+	algorithm string
+	qop       string
+	nonce     string
+	requests  int
+	counts    []string
+}
+
+func (suite *DigestAuthTestSuite) SetupTest() {
+	suite.algorithm, suite.qop, suite.nonce = "MD5", "auth", "n1"
+	suite.requests, suite.counts = 0, nil
+
+	suite.server = httptest.NewServer(http.HandlerFunc(suite.handle))
+}
+
+func (suite *DigestAuthTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+// handle checks the request's Digest credentials for the user snorlax with the
+// password s3cr3t, and echoes the request body.
+func (suite *DigestAuthTestSuite) handle(w http.ResponseWriter,
+	r *http.Request) {
+	suite.mu.Lock()
+	defer suite.mu.Unlock()
+	suite.requests++
+
+	params := parseDigest(r.Header.Get("Authorization"))
+	if params == nil || params["nonce"] != suite.nonce {
+		suite.challenge(w, params != nil)
+		return
+	}
+	suite.counts = append(suite.counts, params["nc"])
+
+	h := md5.New
+	if strings.HasPrefix(suite.algorithm, "SHA-256") {
+		h = sha256.New
+	}
+
+	ha1 := hexHash(h, "snorlax:pokemon@example.org:s3cr3t")
+	if strings.HasSuffix(suite.algorithm, "-sess") {
+		ha1 = hexHash(h, ha1+":"+suite.nonce+":"+params["cnonce"])
+	}
+	ha2 := hexHash(h, r.Method+":"+r.URL.RequestURI())
+
+	want := hexHash(h, ha1+":"+suite.nonce+":"+ha2)
+	if suite.qop != "" {
+		want = hexHash(h, strings.Join([]string{ha1, suite.nonce,
+			params["nc"], params["cnonce"], params["qop"], ha2}, ":"))
+	}
+
+	if params["response"] != want || params["uri"] != r.URL.RequestURI() ||
+		params["opaque"] != "0p4qu3" {
+		suite.challenge(w, false)
+		return
+	}
+
+	EchoHandler(w, r)
+}
+
+func (suite *DigestAuthTestSuite) challenge(w http.ResponseWriter,
+	stale bool) {
+	challenge := fmt.Sprintf(`Digest realm="pokemon@example.org", `+
+		`nonce="%s", opaque="0p4qu3", algorithm=%s`, suite.nonce,
+		suite.algorithm)
+	if suite.qop != "" {
+		challenge += fmt.Sprintf(`, qop="%s"`, suite.qop)
+	}
+	if stale {
+		challenge += ", stale=true"
+	}
+
+	w.Header().Add("WWW-Authenticate", `Basic realm="pokemon@example.org"`)
+	w.Header().Add("WWW-Authenticate", challenge)
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+func (suite *DigestAuthTestSuite) client(password string) snorlax.Client {
+	return snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		AddRequestHook(snorlax.WithDigestAuth(
+			snorlax.NewDigestAuth("snorlax", password)))
+}
+
+func (suite *DigestAuthTestSuite) TestChallenge() {
+	client := suite.client("s3cr3t")
+
+	res, err := client.Get(context.TODO(), "/pokemon",
+		url.Values{"name": {"snorlax"}})
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, res.StatusCode)
+	suite.Require().Equal(2, suite.requests)
+
+	// Later requests are authorized up front, counting the nonce's uses.
+	res, err = client.Get(context.TODO(), "/pokemon", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, res.StatusCode)
+	suite.Require().Equal(3, suite.requests)
+	suite.Require().Equal([]string{"00000001", "00000002"}, suite.counts)
+}
+
+func (suite *DigestAuthTestSuite) TestAlgorithms() {
+	for _, algorithm := range []string{"MD5", "MD5-sess", "SHA-256",
+		"SHA-256-sess"} {
+		suite.Run(algorithm, func() {
+			suite.algorithm = algorithm
+
+			res, err := suite.client("s3cr3t").Post(context.TODO(), "/pokemon",
+				nil, strings.NewReader("snorlax"))
+			suite.Require().NoError(err)
+			suite.Require().Equal(http.StatusOK, res.StatusCode)
+
+			body, err := res.RawBody()
+			suite.Require().NoError(err)
+			suite.Require().Equal("snorlax", fmt.Sprint(body))
+		})
+	}
+}
+
+func (suite *DigestAuthTestSuite) TestNonRewindableBody() {
+	// A body of an unknown type can't be replayed by the request itself.
+	body := struct{ io.Reader }{strings.NewReader("snorlax")}
+
+	res, err := suite.client("s3cr3t").Post(context.TODO(), "/pokemon", nil,
+		body)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, res.StatusCode)
+	suite.Require().Equal(2, suite.requests)
+
+	raw, err := res.RawBody()
+	suite.Require().NoError(err)
+	suite.Require().Equal("snorlax", fmt.Sprint(raw))
+}
+
+func (suite *DigestAuthTestSuite) TestNoQop() {
+	suite.qop = ""
+
+	res, err := suite.client("s3cr3t").Get(context.TODO(), "/pokemon", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, res.StatusCode)
+}
+
+func (suite *DigestAuthTestSuite) TestStaleNonce() {
+	client := suite.client("s3cr3t")
+
+	_, err := client.Get(context.TODO(), "/pokemon", nil)
+	suite.Require().NoError(err)
+
+	suite.mu.Lock()
+	suite.nonce, suite.requests = "n2", 0
+	suite.mu.Unlock()
+
+	res, err := client.Get(context.TODO(), "/pokemon", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, res.StatusCode)
+	suite.Require().Equal(2, suite.requests)
+	suite.Require().Equal("00000001", suite.counts[len(suite.counts)-1])
+}
+
+func (suite *DigestAuthTestSuite) TestWrongPassword() {
+	client := suite.client("wrong")
+
+	res, err := client.Get(context.TODO(), "/pokemon", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusUnauthorized, res.StatusCode)
+	suite.Require().Equal(2, suite.requests)
+
+	// The challenge has already been answered, so it isn't retried.
+	res, err = client.Get(context.TODO(), "/pokemon", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusUnauthorized, res.StatusCode)
+	suite.Require().Equal(3, suite.requests)
+}
+
+func TestDigestAuthTestSuite(t *testing.T) {
+	suite.Run(t, new(DigestAuthTestSuite))
+}
+
+// parseDigest parses the parameters of a Digest Authorization header. It only
+// handles the headers written by snorlax.
+func parseDigest(header string) map[string]string {
+	if !strings.HasPrefix(header, "Digest ") {
+		return nil
+	}
+
+	params := make(map[string]string)
+	for _, p := range strings.Split(strings.TrimPrefix(header, "Digest "),
+		", ") {
+		kv := strings.SplitN(p, "=", 2)
+		params[kv[0]] = strings.Trim(kv[1], `"`)
+	}
+
+	return params
+}
+
+func hexHash(h func() hash.Hash, s string) string {
+	d := h()
+	d.Write([]byte(s))
+	return hex.EncodeToString(d.Sum(nil))
+}
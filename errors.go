@@ -0,0 +1,148 @@
+package snorlax
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// maxErrorBodySize caps the number of bytes of the response body which are
+// kept in an HTTPError.
+const maxErrorBodySize = 4 << 10
+
+// HTTPError describes a response which was received successfully, but whose
+// status code indicates that the request failed. Use errors.As to extract it
+// from errors returned by the client.
+type HTTPError struct {
+	Method string
+
+	// URL is the request URL, with sensitive query parameters and any
+	// password masked by the client's RedactionPolicy.
+	URL string
+
+	StatusCode int
+	Status     string
+	Header     http.Header
+
+	// Body contains up to the first 4KiB of the response body.
+	Body []byte
+
+	// Problem contains the decoded body if the response was an RFC 7807
+	// application/problem+json document.
+	Problem *Problem
+}
+
+// Error satisfies the error interface.
+func (e *HTTPError) Error() string {
+	if e.Problem != nil {
+		return fmt.Sprintf("%s %s: unexpected status %s: %s", e.Method, e.URL,
+			e.Status, e.Problem.Error())
+	}
+
+	return fmt.Sprintf("%s %s: unexpected status %s", e.Method, e.URL,
+		e.Status)
+}
+
+// Unwrap returns the Problem carried by the error, if any, so that it can be
+// extracted using errors.As.
+func (e *HTTPError) Unwrap() error {
+	if e.Problem == nil {
+		return nil
+	}
+
+	return e.Problem
+}
+
+// IsClientError returns whether the status code is within the 4XX range.
+func (e *HTTPError) IsClientError() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500
+}
+
+// IsServerError returns whether the status code is within the 5XX range.
+func (e *HTTPError) IsServerError() bool {
+	return e.StatusCode >= 500 && e.StatusCode < 600
+}
+
+// IsRetryable returns whether the status code indicates a transient failure,
+// which may succeed if the request is sent again.
+func (e *HTTPError) IsRetryable() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusBadGateway, http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// newHTTPError reads the start of the response body, closing it, and builds an
+// HTTPError describing the failed response.
+func newHTTPError(res *Response) *HTTPError {
+	e := HTTPError{
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+		Header:     res.Header,
+	}
+
+	if res.Request != nil {
+		redaction := res.redaction
+		if redaction == nil {
+			redaction = defaultRedaction
+		}
+
+		e.Method = res.Request.Method
+		e.URL = redaction.URL(res.Request.URL)
+	}
+
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+	if err == nil {
+		e.Body = body
+		e.Problem = parseProblem(res.Header.Get("Content-Type"), body)
+	}
+
+	return &e
+}
+
+// IsClientError returns whether err is an HTTPError with a 4XX status code.
+func IsClientError(err error) bool {
+	var e *HTTPError
+	return errors.As(err, &e) && e.IsClientError()
+}
+
+// IsConflict returns whether err is an HTTPError with a 409 status code.
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// IsNotFound returns whether err is an HTTPError with a 404 status code.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsRetryable returns whether err is a transient failure which may succeed if
+// the request is sent again. This is either an HTTPError with a status code
+// such as 429 or 503, or a network error such as a connection reset.
+func IsRetryable(err error) bool {
+	var e *HTTPError
+	if errors.As(err, &e) {
+		return e.IsRetryable()
+	}
+
+	return err != nil && isRetryableError(err)
+}
+
+// IsServerError returns whether err is an HTTPError with a 5XX status code.
+func IsServerError(err error) bool {
+	var e *HTTPError
+	return errors.As(err, &e) && e.IsServerError()
+}
+
+// hasStatus returns whether err is an HTTPError with the status code.
+func hasStatus(err error, code int) bool {
+	var e *HTTPError
+	return errors.As(err, &e) && e.StatusCode == code
+}
@@ -0,0 +1,113 @@
+package snorlax_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/stretchr/testify/suite"
+)
+
+type ErrorsTestSuite struct {
+	suite.Suite
+	client snorlax.Client
+	server *httptest.Server
+}
+
+func (suite *ErrorsTestSuite) SetupSuite() {
+	// The handler responds with the status code given in the path.
+	h := func(w http.ResponseWriter, r *http.Request) {
+		code, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		if err != nil {
+			code = http.StatusBadRequest
+		}
+
+		w.Header().Set("X-Pokemon", "snorlax")
+		w.WriteHeader(code)
+		_, _ = w.Write([]byte(strings.Repeat("z", 10000)))
+	}
+
+	suite.server = httptest.NewServer(http.HandlerFunc(h))
+	suite.client = snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		SetHTTPErrors(true)
+}
+
+func (suite *ErrorsTestSuite) TearDownSuite() {
+	suite.server.Close()
+}
+
+func (suite *ErrorsTestSuite) TestHTTPError() {
+	res, err := suite.client.Get(context.TODO(), "/404", nil)
+	suite.Require().Nil(res)
+
+	var httpErr *snorlax.HTTPError
+	suite.Require().True(errors.As(err, &httpErr))
+	suite.Require().Equal(http.MethodGet, httpErr.Method)
+	suite.Require().Equal(suite.server.URL+"/404", httpErr.URL)
+	suite.Require().Equal(http.StatusNotFound, httpErr.StatusCode)
+	suite.Require().Equal("snorlax", httpErr.Header.Get("X-Pokemon"))
+	suite.Require().Len(httpErr.Body, 4096)
+}
+
+func (suite *ErrorsTestSuite) TestHTTPError_RedactedURL() {
+	_, err := suite.client.Get(context.TODO(), "/404",
+		url.Values{"api_key": {"s3cr3t"}, "name": {"snorlax"}})
+
+	var httpErr *snorlax.HTTPError
+	suite.Require().True(errors.As(err, &httpErr))
+	suite.Require().Equal(suite.server.URL+"/404?api_key=[REDACTED]&"+
+		"name=snorlax", httpErr.URL)
+	suite.Require().NotContains(err.Error(), "s3cr3t")
+
+	// The client's own RedactionPolicy is used.
+	client := suite.client.Clone().SetRedactionPolicy(
+		&snorlax.RedactionPolicy{QueryParams: []string{"name"}})
+	_, err = client.Get(context.TODO(), "/404",
+		url.Values{"name": {"snorlax"}})
+	suite.Require().Error(err)
+	suite.Require().NotContains(err.Error(), "snorlax")
+}
+
+func (suite *ErrorsTestSuite) TestHTTPError_Success() {
+	res, err := suite.client.Get(context.TODO(), "/200", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, res.StatusCode)
+}
+
+func (suite *ErrorsTestSuite) TestHTTPError_Predicates() {
+	tests := []struct {
+		code        int
+		predicate   func(error) bool
+		description string
+	}{
+		{http.StatusNotFound, snorlax.IsNotFound, "not found"},
+		{http.StatusConflict, snorlax.IsConflict, "conflict"},
+		{http.StatusTeapot, snorlax.IsClientError, "client error"},
+		{http.StatusInternalServerError, snorlax.IsServerError, "server error"},
+		{http.StatusServiceUnavailable, snorlax.IsRetryable, "retryable"},
+	}
+
+	for _, test := range tests {
+		_, err := suite.client.Get(context.TODO(),
+			"/"+strconv.Itoa(test.code), nil)
+		suite.Require().True(test.predicate(err), test.description)
+
+		_, err = suite.client.Get(context.TODO(), "/200", nil)
+		suite.Require().False(test.predicate(err), test.description)
+	}
+
+	_, err := suite.client.Get(context.TODO(), "/500", nil)
+	suite.Require().False(snorlax.IsRetryable(err))
+	suite.Require().False(snorlax.IsClientError(err))
+}
+
+func TestErrorsTestSuite(t *testing.T) {
+	suite.Run(t, new(ErrorsTestSuite))
+}
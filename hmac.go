@@ -0,0 +1,336 @@
+package snorlax
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HMACValues are the values which change each time a request is signed by an
+// HMACSigner.
+type HMACValues struct {
+	// Time is when the request is signed, and Timestamp is Time formatted
+	// using the signer's TimestampFormat.
+	Time      time.Time
+	Timestamp string
+
+	// Nonce is a random string which is unique to the signature.
+	Nonce string
+}
+
+// HMACComponent returns a component of the canonical string which an
+// HMACSigner signs.
+type HMACComponent func(req *http.Request, v HMACValues) (string, error)
+
+// HMACSigner signs requests by computing an HMAC of a canonical string, built
+// from the request by joining its Components with the Separator. The encoded
+// signature is written to the request's Headers.
+type HMACSigner struct {
+	// KeyID identifies the Key to the server.
+	KeyID string
+
+	// Key is the shared secret the HMAC is keyed with.
+	Key []byte
+
+	// Hash is the hash function used to compute the HMAC.
+	Hash func() hash.Hash
+
+	// Components are the parts of the canonical string, in order.
+	Components []HMACComponent
+
+	// Separator joins the Components.
+	Separator string
+
+	// Encode encodes the signature.
+	Encode func([]byte) string
+
+	// Headers are set on the request, mapping header names to templates for
+	// their values. The templates may contain the placeholders {signature},
+	// {key_id}, {timestamp} and {nonce}. Headers which don't contain the
+	// signature are set before the canonical string is built, so that it can
+	// include them.
+	Headers map[string]string
+
+	// TimestampFormat is the time layout used to format the timestamp. If
+	// empty, the timestamp is the number of seconds since the Unix epoch.
+	TimestampFormat string
+
+	// Now returns the time requests are signed at. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+// NewHMACSigner returns an HMACSigner which signs the components using
+// HMAC-SHA256, joining them with newlines, and writes the hex encoded
+// signature to the X-Signature header.
+func NewHMACSigner(keyID string, key []byte,
+	components ...HMACComponent) *HMACSigner {
+	return &HMACSigner{
+		KeyID:      keyID,
+		Key:        key,
+		Hash:       sha256.New,
+		Components: components,
+		Separator:  "\n",
+		Encode:     hex.EncodeToString,
+		Headers:    map[string]string{"X-Signature": "{signature}"},
+	}
+}
+
+// Sign satisfies the Signer interface.
+func (s *HMACSigner) Sign(req *http.Request) error {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+
+	nonce, err := randomHex(16)
+	if err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	v := HMACValues{Time: now(), Nonce: nonce}
+	if s.TimestampFormat == "" {
+		v.Timestamp = strconv.FormatInt(v.Time.Unix(), 10)
+	} else {
+		v.Timestamp = v.Time.Format(s.TimestampFormat)
+	}
+
+	replacer := strings.NewReplacer(
+		"{key_id}", s.KeyID,
+		"{timestamp}", v.Timestamp,
+		"{nonce}", v.Nonce,
+	)
+
+	for name, template := range s.Headers {
+		if !strings.Contains(template, "{signature}") {
+			req.Header.Set(name, replacer.Replace(template))
+		}
+	}
+
+	components := make([]string, len(s.Components))
+	for i, component := range s.Components {
+		if components[i], err = component(req, v); err != nil {
+			return err
+		}
+	}
+
+	h := s.Hash
+	if h == nil {
+		h = sha256.New
+	}
+
+	mac := hmac.New(h, s.Key)
+	mac.Write([]byte(strings.Join(components, s.Separator)))
+
+	encode := s.Encode
+	if encode == nil {
+		encode = hex.EncodeToString
+	}
+	signature := encode(mac.Sum(nil))
+
+	for name, template := range s.Headers {
+		if strings.Contains(template, "{signature}") {
+			req.Header.Set(name, strings.ReplaceAll(replacer.Replace(template),
+				"{signature}", signature))
+		}
+	}
+
+	return nil
+}
+
+// HMACMethod is the request's method.
+func HMACMethod() HMACComponent {
+	return func(req *http.Request, _ HMACValues) (string, error) {
+		return strings.ToUpper(req.Method), nil
+	}
+}
+
+// HMACPath is the request's escaped path.
+func HMACPath() HMACComponent {
+	return func(req *http.Request, _ HMACValues) (string, error) {
+		if path := req.URL.EscapedPath(); path != "" {
+			return path, nil
+		}
+
+		return "/", nil
+	}
+}
+
+// HMACQuery is the request's query string, sorted by key and then by value.
+func HMACQuery() HMACComponent {
+	return func(req *http.Request, _ HMACValues) (string, error) {
+		query := req.URL.Query()
+		for _, values := range query {
+			sort.Strings(values)
+		}
+
+		return query.Encode(), nil
+	}
+}
+
+// HMACHost is the request's host.
+func HMACHost() HMACComponent {
+	return func(req *http.Request, _ HMACValues) (string, error) {
+		return requestHost(req), nil
+	}
+}
+
+// HMACHeader is the value of the request's header. Multiple values are joined
+// with commas.
+func HMACHeader(name string) HMACComponent {
+	return func(req *http.Request, _ HMACValues) (string, error) {
+		return strings.Join(req.Header.Values(name), ","), nil
+	}
+}
+
+// HMACHeaders are the request's headers, as lines of lower case names and
+// values separated by colons. Multiple values are joined with commas.
+func HMACHeaders(names ...string) HMACComponent {
+	return func(req *http.Request, _ HMACValues) (string, error) {
+		lines := make([]string, len(names))
+		for i, name := range names {
+			lines[i] = strings.ToLower(name) + ":" +
+				strings.Join(req.Header.Values(name), ",")
+		}
+
+		return strings.Join(lines, "\n"), nil
+	}
+}
+
+// HMACBody is the encoded hash of the request's body. The body is not
+// consumed.
+func HMACBody(h func() hash.Hash, encode func([]byte) string) HMACComponent {
+	return func(req *http.Request, _ HMACValues) (string, error) {
+		body, err := peekBody(req)
+		if err != nil {
+			return "", err
+		}
+
+		d := h()
+		d.Write(body)
+		return encode(d.Sum(nil)), nil
+	}
+}
+
+// HMACTimestamp is the time the request is signed at.
+func HMACTimestamp() HMACComponent {
+	return func(_ *http.Request, v HMACValues) (string, error) {
+		return v.Timestamp, nil
+	}
+}
+
+// HMACNonce is a random string which is unique to the signature.
+func HMACNonce() HMACComponent {
+	return func(_ *http.Request, v HMACValues) (string, error) {
+		return v.Nonce, nil
+	}
+}
+
+// HMACLiteral is the string s.
+func HMACLiteral(s string) HMACComponent {
+	return func(*http.Request, HMACValues) (string, error) {
+		return s, nil
+	}
+}
+
+// NewHTTPMessageSigner returns an HMACSigner which signs requests using HTTP
+// Message Signatures, as described in RFC 9421, with the hmac-sha256
+// algorithm. The signature is labelled with label, and covers the components,
+// which are either derived components such as @method, @authority, @path and
+// @query, or the lower case names of headers.
+//
+// To cover the body, add a Content-Digest header using ContentDigest before
+// signing, and include content-digest in the components.
+func NewHTTPMessageSigner(label, keyID string, key []byte,
+	components ...string) *HMACSigner {
+	quoted := make([]string, len(components))
+	lines := make([]HMACComponent, 0, len(components)+1)
+	for i, c := range components {
+		c = strings.ToLower(c)
+		quoted[i] = quote(c)
+		lines = append(lines, messageComponent(c))
+	}
+
+	params := "(" + strings.Join(quoted, " ") + ");created={timestamp};" +
+		"keyid=" + quote(keyID)
+	lines = append(lines, func(_ *http.Request, v HMACValues) (string,
+		error) {
+		return `"@signature-params": ` +
+			strings.ReplaceAll(params, "{timestamp}", v.Timestamp), nil
+	})
+
+	return &HMACSigner{
+		KeyID:      keyID,
+		Key:        key,
+		Hash:       sha256.New,
+		Components: lines,
+		Separator:  "\n",
+		Encode:     base64.StdEncoding.EncodeToString,
+		Headers: map[string]string{
+			"Signature-Input": label + "=" + params,
+			"Signature":       label + "=:{signature}:",
+		},
+	}
+}
+
+// messageComponent returns a line of an RFC 9421 signature base.
+func messageComponent(name string) HMACComponent {
+	return func(req *http.Request, _ HMACValues) (string, error) {
+		var value string
+		switch name {
+		case "@method":
+			value = req.Method
+		case "@authority":
+			value = strings.ToLower(requestHost(req))
+		case "@scheme":
+			value = strings.ToLower(req.URL.Scheme)
+		case "@target-uri":
+			value = req.URL.String()
+		case "@request-target":
+			value = req.URL.RequestURI()
+		case "@path":
+			value = req.URL.EscapedPath()
+			if value == "" {
+				value = "/"
+			}
+		case "@query":
+			value = "?" + req.URL.RawQuery
+		default:
+			if strings.HasPrefix(name, "@") {
+				return "", fmt.Errorf("unsupported message component %s",
+					name)
+			}
+
+			values := req.Header.Values(name)
+			if len(values) == 0 {
+				return "", errors.New("message component " + name +
+					" is missing")
+			}
+
+			trimmed := make([]string, len(values))
+			for i, v := range values {
+				trimmed[i] = strings.TrimSpace(v)
+			}
+			value = strings.Join(trimmed, ", ")
+		}
+
+		return quote(name) + ": " + value, nil
+	}
+}
+
+// requestHost returns the host the request is sent to.
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+
+	return req.URL.Host
+}
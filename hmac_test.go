@@ -0,0 +1,154 @@
+package snorlax_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRequest returns the test request used by the examples in RFC 9421.
+func newTestRequest(t *testing.T) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost,
+		"http://example.com/foo?param=Value&Pet=dog",
+		strings.NewReader(`{"hello": "world"}`))
+	req.Header.Set("Date", "Tue, 20 Apr 2021 02:07:55 GMT")
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
+
+func TestContentDigest(t *testing.T) {
+	req := newTestRequest(t)
+
+	require.NoError(t, snorlax.ContentDigest("sha-256").Sign(req))
+	require.Equal(t, "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:",
+		req.Header.Get("Content-Digest"))
+
+	require.NoError(t, snorlax.ContentDigest("sha-512").Sign(req))
+	require.Equal(t, "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+"+
+		"AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:",
+		req.Header.Get("Content-Digest"))
+
+	require.NoError(t, snorlax.DigestHeader("sha-256").Sign(req))
+	require.Equal(t, "SHA-256=X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=",
+		req.Header.Get("Digest"))
+
+	require.Error(t, snorlax.ContentDigest("md5").Sign(req))
+
+	// The body can still be sent.
+	body, err := ioutil.ReadAll(req.Body)
+	require.NoError(t, err)
+	require.Equal(t, `{"hello": "world"}`, string(body))
+}
+
+// The test case is the HMAC-SHA256 example in RFC 9421 appendix B.2.5.
+func TestHTTPMessageSigner(t *testing.T) {
+	key, err := base64.StdEncoding.DecodeString("uzvJfB4u3N0Jy4T7NZ75MDVcr8zS" +
+		"TInedJtkgcu46YW4XByzNJjxBdtjUkdJPBtbmHhIDi6pcl8jsasjlTMtDQ==")
+	require.NoError(t, err)
+
+	signer := snorlax.NewHTTPMessageSigner("sig-b26", "test-shared-secret",
+		key, "date", "@authority", "content-type")
+	signer.Now = func() time.Time { return time.Unix(1618884473, 0) }
+
+	req := newTestRequest(t)
+	require.NoError(t, signer.Sign(req))
+	require.Equal(t, `sig-b26=("date" "@authority" "content-type");`+
+		`created=1618884473;keyid="test-shared-secret"`,
+		req.Header.Get("Signature-Input"))
+	require.Equal(t, "sig-b26=:pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=:",
+		req.Header.Get("Signature"))
+}
+
+func TestHTTPMessageSigner_MissingHeader(t *testing.T) {
+	signer := snorlax.NewHTTPMessageSigner("sig1", "key", []byte("secret"),
+		"@method", "content-digest")
+
+	require.Error(t, signer.Sign(newTestRequest(t)))
+}
+
+func TestHMACSigner(t *testing.T) {
+	signer := snorlax.NewHMACSigner("partner", []byte("s3cr3t"),
+		snorlax.HMACMethod(),
+		snorlax.HMACPath(),
+		snorlax.HMACQuery(),
+		snorlax.HMACHeaders("Content-Type", "X-Timestamp"),
+		snorlax.HMACBody(sha256.New, hex.EncodeToString),
+		snorlax.HMACNonce(),
+	)
+	signer.Encode = base64.StdEncoding.EncodeToString
+	signer.TimestampFormat = time.RFC3339
+	signer.Now = func() time.Time {
+		return time.Date(2021, 4, 20, 2, 7, 55, 0, time.UTC)
+	}
+	signer.Headers = map[string]string{
+		"X-Timestamp":   "{timestamp}",
+		"X-Nonce":       "{nonce}",
+		"Authorization": "HMAC {key_id}:{signature}",
+	}
+
+	req := newTestRequest(t)
+	require.NoError(t, signer.Sign(req))
+	require.Equal(t, "2021-04-20T02:07:55Z", req.Header.Get("X-Timestamp"))
+
+	nonce := req.Header.Get("X-Nonce")
+	require.Len(t, nonce, 32)
+
+	body := sha256.Sum256([]byte(`{"hello": "world"}`))
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	mac.Write([]byte(strings.Join([]string{
+		"POST",
+		"/foo",
+		"Pet=dog&param=Value",
+		"content-type:application/json\nx-timestamp:2021-04-20T02:07:55Z",
+		hex.EncodeToString(body[:]),
+		nonce,
+	}, "\n")))
+
+	require.Equal(t, "HMAC partner:"+
+		base64.StdEncoding.EncodeToString(mac.Sum(nil)),
+		req.Header.Get("Authorization"))
+}
+
+func TestClient_HTTPMessageSigner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Header.Get("Content-Digest") + "\n" +
+				r.Header.Get("Signature-Input")))
+		}))
+	defer server.Close()
+
+	client := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(server.URL).
+		AddRequestHook(snorlax.WithSigner(
+			snorlax.ContentDigest("sha-256"),
+			snorlax.NewHTTPMessageSigner("sig1", "key", []byte("secret"),
+				"@method", "@path", "content-digest"),
+		))
+
+	res, err := client.Post(context.TODO(), "/pokemon", nil,
+		strings.NewReader(`{"hello": "world"}`))
+	require.NoError(t, err)
+
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	lines := strings.Split(string(body), "\n")
+	require.Equal(t, "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:",
+		lines[0])
+	require.True(t, strings.HasPrefix(lines[1],
+		`sig1=("@method" "@path" "content-digest");created=`))
+}
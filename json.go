@@ -0,0 +1,102 @@
+package snorlax
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// jsonContentType is the media type sent and accepted by the JSON helpers.
+const jsonContentType = "application/json"
+
+// GetJSON performs a Get request using the client, and decodes the JSON
+// response body into a T. Responses with a non-2XX status code are returned as
+// an *HTTPError.
+func GetJSON[T any](ctx context.Context, c Client, target string,
+	query url.Values, hooks ...RequestHook) (T, error) {
+	return doJSON[T](ctx, c, http.MethodGet, target, query, nil, hooks...)
+}
+
+// DeleteJSON performs a Delete request using the client, and decodes the JSON
+// response body into a T. Responses with a non-2XX status code are returned as
+// an *HTTPError.
+func DeleteJSON[T any](ctx context.Context, c Client, target string,
+	query url.Values, hooks ...RequestHook) (T, error) {
+	return doJSON[T](ctx, c, http.MethodDelete, target, query, nil,
+		hooks...)
+}
+
+// PatchJSON encodes body as JSON, performs a Patch request using the client,
+// and decodes the JSON response body into a Resp. Responses with a non-2XX
+// status code are returned as an *HTTPError.
+func PatchJSON[Req, Resp any](ctx context.Context, c Client, target string,
+	query url.Values, body Req, hooks ...RequestHook) (Resp, error) {
+	return doJSON[Resp](ctx, c, http.MethodPatch, target, query, body,
+		hooks...)
+}
+
+// PostJSON encodes body as JSON, performs a Post request using the client, and
+// decodes the JSON response body into a Resp. Responses with a non-2XX status
+// code are returned as an *HTTPError.
+func PostJSON[Req, Resp any](ctx context.Context, c Client, target string,
+	query url.Values, body Req, hooks ...RequestHook) (Resp, error) {
+	return doJSON[Resp](ctx, c, http.MethodPost, target, query, body,
+		hooks...)
+}
+
+// PutJSON encodes body as JSON, performs a Put request using the client, and
+// decodes the JSON response body into a Resp. Responses with a non-2XX status
+// code are returned as an *HTTPError.
+func PutJSON[Req, Resp any](ctx context.Context, c Client, target string,
+	query url.Values, body Req, hooks ...RequestHook) (Resp, error) {
+	return doJSON[Resp](ctx, c, http.MethodPut, target, query, body,
+		hooks...)
+}
+
+// doJSON performs a JSON request, encoding body if it isn't nil, and decodes
+// the response into a T.
+func doJSON[T any](ctx context.Context, c Client, method, target string,
+	query url.Values, body interface{}, hooks ...RequestHook) (T, error) {
+	var out T
+
+	// The JSON headers are set first, so that the caller's hooks are able to
+	// override them.
+	jsonHooks := []RequestHook{WithHeader("Accept", jsonContentType)}
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return out, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+
+		reqBody = bytes.NewReader(data)
+		jsonHooks = append(jsonHooks, WithHeader("Content-Type",
+			jsonContentType))
+	}
+
+	res, err := c.Do(ctx, method, target, query, reqBody,
+		append(jsonHooks, hooks...)...)
+	if err != nil {
+		return out, err
+	}
+
+	if !res.IsSuccess() {
+		return out, newHTTPError(res)
+	}
+
+	if res.StatusCode == http.StatusNoContent || method == http.MethodHead {
+		res.Body.Close()
+		return out, nil
+	}
+
+	if err = res.JSON(&out); err != nil {
+		return out, err
+	}
+
+	return out, nil
+}
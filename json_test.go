@@ -0,0 +1,94 @@
+package snorlax_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/stretchr/testify/suite"
+)
+
+type Pokemon struct {
+	Name   string `json:"name"`
+	Number int    `json:"number"`
+}
+
+type JSONTestSuite struct {
+	suite.Suite
+	client snorlax.Client
+	server *httptest.Server
+}
+
+func (suite *JSONTestSuite) SetupSuite() {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pokemon/143":
+			w.Header().Set("Content-Type", r.Header.Get("Accept"))
+			_ = json.NewEncoder(w).Encode(Pokemon{"snorlax", 143})
+		case "/pokemon":
+			if r.Header.Get("Content-Type") != "application/json" {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+
+			var p Pokemon
+			_ = json.NewDecoder(r.Body).Decode(&p)
+			p.Number++
+			_ = json.NewEncoder(w).Encode(p)
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("no such pokemon"))
+		}
+	}
+
+	suite.server = httptest.NewServer(http.HandlerFunc(h))
+	suite.client = snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL)
+}
+
+func (suite *JSONTestSuite) TearDownSuite() {
+	suite.server.Close()
+}
+
+func (suite *JSONTestSuite) TestGetJSON() {
+	p, err := snorlax.GetJSON[Pokemon](context.TODO(), suite.client,
+		"/pokemon/143", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(Pokemon{"snorlax", 143}, p)
+}
+
+func (suite *JSONTestSuite) TestPostJSON() {
+	p, err := snorlax.PostJSON[Pokemon, *Pokemon](context.TODO(),
+		suite.client, "/pokemon", nil, Pokemon{"munchlax", 445})
+	suite.Require().NoError(err)
+	suite.Require().Equal(&Pokemon{"munchlax", 446}, p)
+}
+
+func (suite *JSONTestSuite) TestPutJSON_NoContent() {
+	p, err := snorlax.PutJSON[Pokemon, *Pokemon](context.TODO(),
+		suite.client, "/empty", nil, Pokemon{"snorlax", 143})
+	suite.Require().NoError(err)
+	suite.Require().Nil(p)
+}
+
+func (suite *JSONTestSuite) TestGetJSON_HTTPError() {
+	_, err := snorlax.GetJSON[Pokemon](context.TODO(), suite.client,
+		"/pokemon/0", nil)
+	suite.Require().Error(err)
+
+	var httpErr *snorlax.HTTPError
+	suite.Require().True(errors.As(err, &httpErr))
+	suite.Require().Equal(http.StatusNotFound, httpErr.StatusCode)
+	suite.Require().Equal(http.MethodGet, httpErr.Method)
+	suite.Require().Equal("no such pokemon", string(httpErr.Body))
+}
+
+func TestJSONTestSuite(t *testing.T) {
+	suite.Run(t, new(JSONTestSuite))
+}
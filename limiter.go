@@ -0,0 +1,173 @@
+package snorlax
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimitedError is returned when the client's RateLimiter refuses to send a
+// request, either because it is configured to fail fast or because waiting for
+// capacity would outlast the request's context deadline.
+type RateLimitedError struct {
+	// Key identifies the bucket which was exhausted.
+	Key string
+
+	// Delay is the time the request would have had to wait to be sent.
+	Delay time.Duration
+}
+
+// Error satisfies the error interface.
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %q: next request allowed "+
+		"in %s", e.Key, e.Delay)
+}
+
+// RateLimiter is a client-side token bucket rate limiter. Requests consume one
+// token each, and tokens are replenished at a fixed rate up to the burst size.
+// A RateLimiter is safe for concurrent use, and may be shared between clients
+// to enforce a combined limit. The zero value doesn't limit requests; use
+// NewRateLimiter to set the rate.
+type RateLimiter struct {
+	// KeyFunc optionally partitions requests into separate buckets, each of
+	// which is limited independently. Use ByHost to limit each host
+	// separately. By default all requests share a single bucket.
+	KeyFunc func(r *http.Request) string
+
+	// FailFast makes the limiter return a *RateLimitedError immediately,
+	// instead of waiting, when a bucket is exhausted.
+	FailFast bool
+
+	rate    float64
+	burst   int
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// NewRateLimiter constructs a RateLimiter which allows rate requests per
+// second, with bursts of up to burst requests. A burst of less than 1 allows
+// a single request at a time. It panics if rate isn't positive, since no
+// requests would ever be allowed after the first burst.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if !(rate > 0) {
+		panic(fmt.Sprintf("snorlax: non-positive rate %v for NewRateLimiter",
+			rate))
+	}
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// ByHost is a RateLimiter KeyFunc which limits each host independently.
+func ByHost(r *http.Request) string {
+	return r.URL.Host
+}
+
+// Wait blocks until the request is allowed to be sent, returning how long it
+// waited. It returns a *RateLimitedError without consuming capacity if the
+// limiter fails fast or the wait would outlast the context's deadline, and the
+// context's error if it is done while waiting.
+func (l *RateLimiter) Wait(ctx context.Context, r *http.Request) (
+	time.Duration, error) {
+	if l.burst == 0 && l.rate == 0 {
+		return 0, nil
+	}
+
+	key := ""
+	if l.KeyFunc != nil {
+		key = l.KeyFunc(r)
+	}
+
+	now := time.Now()
+	b := l.bucket(key, now)
+
+	l.mu.Lock()
+	delay := b.reserve(now, l.rate, l.burst)
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return 0, nil
+	}
+
+	// A bucket which is never refilled can't be waited for.
+	deadline, hasDeadline := ctx.Deadline()
+	if l.FailFast || delay == never ||
+		(hasDeadline && now.Add(delay).After(deadline)) {
+		l.cancel(b)
+		return 0, &RateLimitedError{Key: key, Delay: delay}
+	}
+
+	if err := sleep(ctx, delay); err != nil {
+		l.cancel(b)
+		return 0, err
+	}
+
+	return delay, nil
+}
+
+// bucket returns the token bucket for key, creating a full one if needed.
+func (l *RateLimiter) bucket(key string, now time.Time) *tokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.buckets == nil {
+		l.buckets = make(map[string]*tokenBucket)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+
+	return b
+}
+
+// cancel returns a reserved token to the bucket.
+func (l *RateLimiter) cancel(b *tokenBucket) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b.tokens = math.Min(b.tokens+1, float64(l.burst))
+}
+
+// never is the delay of a reservation which will never become available.
+const never = time.Duration(math.MaxInt64)
+
+// tokenBucket holds the state of a single rate limited key.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes a token from the bucket, returning how long the caller must
+// wait before the token becomes available.
+func (b *tokenBucket) reserve(now time.Time, rate float64,
+	burst int) time.Duration {
+	if now.After(b.last) {
+		elapsed := now.Sub(b.last).Seconds()
+		b.tokens = math.Min(b.tokens+elapsed*rate, float64(burst))
+		b.last = now
+	}
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	if rate <= 0 {
+		return never
+	}
+
+	return time.Duration(-b.tokens / rate * float64(time.Second))
+}
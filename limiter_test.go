@@ -0,0 +1,132 @@
+package snorlax_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimiterTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+func (suite *RateLimiterTestSuite) SetupSuite() {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	suite.server = httptest.NewServer(http.HandlerFunc(h))
+}
+
+func (suite *RateLimiterTestSuite) TearDownSuite() {
+	suite.server.Close()
+}
+
+func (suite *RateLimiterTestSuite) TestRateLimiter_Waits() {
+	client := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		SetRateLimiter(snorlax.NewRateLimiter(20, 1))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := client.Get(context.TODO(), "/limited", nil)
+		suite.Require().NoError(err)
+	}
+
+	suite.Require().True(time.Since(start) >= 90*time.Millisecond)
+}
+
+func (suite *RateLimiterTestSuite) TestRateLimiter_FailFast() {
+	limiter := snorlax.NewRateLimiter(1, 2)
+	limiter.FailFast = true
+
+	client := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		SetRateLimiter(limiter)
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Get(context.TODO(), "/limited", nil)
+		suite.Require().NoError(err)
+	}
+
+	_, err := client.Get(context.TODO(), "/limited", nil)
+
+	var limitErr *snorlax.RateLimitedError
+	suite.Require().True(errors.As(err, &limitErr))
+	suite.Require().True(limitErr.Delay > 0)
+}
+
+func (suite *RateLimiterTestSuite) TestRateLimiter_Deadline() {
+	client := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		SetRateLimiter(snorlax.NewRateLimiter(0.1, 1))
+
+	_, err := client.Get(context.TODO(), "/limited", nil)
+	suite.Require().NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.Get(ctx, "/limited", nil)
+
+	var limitErr *snorlax.RateLimitedError
+	suite.Require().True(errors.As(err, &limitErr))
+	suite.Require().True(time.Since(start) < time.Second)
+}
+
+func (suite *RateLimiterTestSuite) TestRateLimiter_ByHost() {
+	other := httptest.NewServer(suite.server.Config.Handler)
+	defer other.Close()
+
+	limiter := snorlax.NewRateLimiter(0.1, 1)
+	limiter.KeyFunc = snorlax.ByHost
+	limiter.FailFast = true
+
+	client := snorlax.NewClient(snorlax.Defaults()).SetRateLimiter(limiter)
+
+	for _, server := range []*httptest.Server{suite.server, other} {
+		_, err := client.Get(context.TODO(), server.URL, nil)
+		suite.Require().NoError(err)
+	}
+
+	_, err := client.Get(context.TODO(), other.URL, nil)
+
+	var limitErr *snorlax.RateLimitedError
+	suite.Require().True(errors.As(err, &limitErr))
+	suite.Require().Equal(other.Listener.Addr().String(), limitErr.Key)
+}
+
+func (suite *RateLimiterTestSuite) TestRateLimiter_ZeroValue() {
+	client := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		SetRateLimiter(&snorlax.RateLimiter{})
+
+	// The zero value doesn't limit requests, even without a deadline.
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		_, err := client.Get(context.TODO(), "/limited", nil)
+		suite.Require().NoError(err)
+	}
+
+	suite.Require().True(time.Since(start) < time.Second)
+}
+
+func (suite *RateLimiterTestSuite) TestRateLimiter_InvalidRate() {
+	for _, rate := range []float64{0, -1} {
+		suite.Require().Panics(func() {
+			snorlax.NewRateLimiter(rate, 1)
+		})
+	}
+}
+
+func TestRateLimiterTestSuite(t *testing.T) {
+	suite.Run(t, new(RateLimiterTestSuite))
+}
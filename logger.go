@@ -0,0 +1,190 @@
+package snorlax
+
+import (
+	"net/url"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Level is the severity of a log message. The levels mirror those of logrus.
+type Level uint32
+
+const (
+	// PanicLevel is the lowest level, at which Snorlax writes no logs.
+	PanicLevel Level = iota
+	FatalLevel
+	ErrorLevel
+	WarnLevel
+	InfoLevel
+	DebugLevel
+	TraceLevel
+)
+
+// String satisfies the fmt.Stringer interface.
+func (l Level) String() string {
+	switch l {
+	case PanicLevel:
+		return "panic"
+	case FatalLevel:
+		return "fatal"
+	case ErrorLevel:
+		return "error"
+	case WarnLevel:
+		return "warning"
+	case InfoLevel:
+		return "info"
+	case DebugLevel:
+		return "debug"
+	case TraceLevel:
+		return "trace"
+	default:
+		return "unknown"
+	}
+}
+
+// Fields are the structured key value pairs attached to a log message.
+type Fields map[string]interface{}
+
+// Logger is the interface through which a Client writes its logs. Use
+// NewLogrusLogger, NewSlogLogger (with Go 1.21 or later) or NopLogger, or
+// adapt your own logging library.
+type Logger interface {
+	// Log writes the message and fields at the level, if the level is
+	// enabled.
+	Log(level Level, msg string, fields Fields)
+
+	// SetLevel sets the most verbose level of messages which are written.
+	SetLevel(level Level)
+}
+
+// NewLogrusLogger adapts a logrus.Logger to the Logger interface. Messages are
+// written at all levels until SetLevel is called, leaving the logrus.Logger's
+// own level to decide which are kept. SetLevel never changes the
+// logrus.Logger's level, so the rest of the program's logging is unaffected.
+func NewLogrusLogger(l *logrus.Logger) Logger {
+	return &logrusLogger{l: l, level: uint32(TraceLevel)}
+}
+
+type logrusLogger struct {
+	l     *logrus.Logger
+	level uint32
+}
+
+// Log satisfies the Logger interface.
+func (l *logrusLogger) Log(level Level, msg string, fields Fields) {
+	if level > Level(atomic.LoadUint32(&l.level)) {
+		return
+	}
+
+	// logrus panics and exits at its two lowest levels, which a library
+	// should never do on behalf of its caller.
+	if level <= FatalLevel {
+		level = ErrorLevel
+	}
+
+	l.l.WithFields(logrus.Fields(fields)).Log(logrus.Level(level), msg)
+}
+
+// SetLevel satisfies the Logger interface.
+func (l *logrusLogger) SetLevel(level Level) {
+	atomic.StoreUint32(&l.level, uint32(level))
+}
+
+// clone returns a Logger which writes to the same logrus.Logger, but whose
+// level can be set without affecting the original.
+func (l *logrusLogger) clone() Logger {
+	return &logrusLogger{l: l.l, level: atomic.LoadUint32(&l.level)}
+}
+
+// NopLogger returns a Logger which discards all messages.
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+// Log satisfies the Logger interface.
+func (nopLogger) Log(Level, string, Fields) {}
+
+// SetLevel satisfies the Logger interface.
+func (nopLogger) SetLevel(Level) {}
+
+// cloneLogger returns a Logger which writes to the same destination as logger,
+// but whose level can be set without affecting the original, if the logger
+// supports it. Other Loggers are shared.
+func cloneLogger(logger Logger) Logger {
+	if l, ok := logger.(interface{ clone() Logger }); ok {
+		return l.clone()
+	}
+
+	return logger
+}
+
+// logEntry accumulates fields for a log message, providing the levelled
+// helpers the client logs with. Sensitive values are masked according to the
+// entry's RedactionPolicy.
+type logEntry struct {
+	logger    Logger
+	fields    Fields
+	redaction *RedactionPolicy
+}
+
+// WithField returns a copy of the entry with the field added.
+func (e logEntry) WithField(key string, value interface{}) logEntry {
+	return e.WithFields(Fields{key: value})
+}
+
+// WithFields returns a copy of the entry with the fields added.
+func (e logEntry) WithFields(fields Fields) logEntry {
+	merged := make(Fields, len(e.fields)+len(fields))
+	for k, v := range e.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+
+	return logEntry{logger: e.logger, fields: merged, redaction: e.redaction}
+}
+
+// WithError returns a copy of the entry with the error's redacted message
+// added.
+func (e logEntry) WithError(err error) logEntry {
+	return e.WithField("error", e.redaction.Error(err))
+}
+
+// WithHeader returns a copy of the entry with the header's redacted values
+// added.
+func (e logEntry) WithHeader(key string, values ...string) logEntry {
+	return e.WithFields(Fields{
+		"key":   key,
+		"value": e.redaction.Header(key, values),
+	})
+}
+
+// WithURL returns a copy of the entry with the redacted URL added under key.
+func (e logEntry) WithURL(key string, u *url.URL) logEntry {
+	return e.WithField(key, e.redaction.URL(u))
+}
+
+// WithRawURL returns a copy of the entry with the redacted URL, given as a
+// string, added under key.
+func (e logEntry) WithRawURL(key string, raw string) logEntry {
+	return e.WithField(key, e.redaction.rawURL(raw))
+}
+
+// Trace writes the message at TraceLevel.
+func (e logEntry) Trace(msg string) {
+	e.logger.Log(TraceLevel, msg, e.fields)
+}
+
+// Debug writes the message at DebugLevel.
+func (e logEntry) Debug(msg string) {
+	e.logger.Log(DebugLevel, msg, e.fields)
+}
+
+// Warn writes the message at WarnLevel.
+func (e logEntry) Warn(msg string) {
+	e.logger.Log(WarnLevel, msg, e.fields)
+}
@@ -0,0 +1,63 @@
+//go:build go1.21
+
+package snorlax
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// NewSlogLogger adapts a slog.Logger to the Logger interface. Messages are
+// written at all levels until SetLevel is called, leaving the logger's handler
+// to decide which are kept. TraceLevel messages are written at
+// slog.LevelDebug-4.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return &slogLogger{l: l, level: uint32(TraceLevel)}
+}
+
+type slogLogger struct {
+	l     *slog.Logger
+	level uint32
+}
+
+// Log satisfies the Logger interface.
+func (l *slogLogger) Log(level Level, msg string, fields Fields) {
+	if level > Level(atomic.LoadUint32(&l.level)) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, len(fields))
+	for k, v := range fields {
+		attrs = append(attrs, slog.Any(k, v))
+	}
+
+	l.l.LogAttrs(context.Background(), slogLevel(level), msg, attrs...)
+}
+
+// SetLevel satisfies the Logger interface.
+func (l *slogLogger) SetLevel(level Level) {
+	atomic.StoreUint32(&l.level, uint32(level))
+}
+
+// clone returns a copy of the logger whose level can be set without affecting
+// the original.
+func (l *slogLogger) clone() Logger {
+	return &slogLogger{l: l.l, level: atomic.LoadUint32(&l.level)}
+}
+
+// slogLevel maps a Level to the equivalent slog.Level.
+func slogLevel(level Level) slog.Level {
+	switch level {
+	case PanicLevel, FatalLevel, ErrorLevel:
+		return slog.LevelError
+	case WarnLevel:
+		return slog.LevelWarn
+	case InfoLevel:
+		return slog.LevelInfo
+	case DebugLevel:
+		return slog.LevelDebug
+	default:
+		return slog.LevelDebug - 4
+	}
+}
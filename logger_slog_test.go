@@ -0,0 +1,31 @@
+//go:build go1.21
+
+package snorlax_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+
+	"github.com/nickcorin/snorlax"
+)
+
+func (suite *LoggerTestSuite) TestSlogLogger() {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug - 4,
+	})
+
+	client := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		SetLogger(snorlax.NewSlogLogger(slog.New(handler))).
+		SetLogLevel(snorlax.DebugLevel)
+
+	_, err := client.Get(context.TODO(), "/pokemon", nil)
+	suite.Require().NoError(err)
+
+	suite.Require().Contains(buf.String(), `"msg":"request complete"`)
+	suite.Require().Contains(buf.String(), `"level":"DEBUG"`)
+	suite.Require().Contains(buf.String(), `"status_code":200`)
+	suite.Require().NotContains(buf.String(), "performing request")
+}
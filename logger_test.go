@@ -0,0 +1,86 @@
+package snorlax_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+type LoggerTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+func (suite *LoggerTestSuite) SetupSuite() {
+	suite.server = httptest.NewServer(http.HandlerFunc(EchoHandler))
+}
+
+func (suite *LoggerTestSuite) TearDownSuite() {
+	suite.server.Close()
+}
+
+func (suite *LoggerTestSuite) TestLogrusLogger() {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetLevel(logrus.TraceLevel)
+
+	client := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		SetLogger(snorlax.NewLogrusLogger(logger))
+
+	_, err := client.Get(context.TODO(), "/pokemon", nil)
+	suite.Require().NoError(err)
+	suite.Require().Empty(buf.String())
+
+	// The client's level never changes the level of the program's logger.
+	suite.Require().Equal(logrus.TraceLevel, logger.GetLevel())
+	client.SetLogLevel(snorlax.TraceLevel)
+	suite.Require().Equal(logrus.TraceLevel, logger.GetLevel())
+
+	_, err = client.Get(context.TODO(), "/pokemon", nil)
+	suite.Require().NoError(err)
+	suite.Require().Contains(buf.String(), "performing request")
+	suite.Require().Contains(buf.String(), "request complete")
+}
+
+func (suite *LoggerTestSuite) TestLogrusLogger_Level() {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetLevel(logrus.InfoLevel)
+
+	client := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		SetLogger(snorlax.NewLogrusLogger(logger)).
+		SetLogLevel(snorlax.TraceLevel)
+
+	// The program's logger still filters the client's messages.
+	_, err := client.Get(context.TODO(), "/pokemon", nil)
+	suite.Require().NoError(err)
+	suite.Require().Empty(buf.String())
+	suite.Require().Equal(logrus.InfoLevel, logger.GetLevel())
+
+	logger.Info("still logging")
+	suite.Require().Contains(buf.String(), "still logging")
+}
+
+func (suite *LoggerTestSuite) TestNopLogger() {
+	client := snorlax.NewClient(&snorlax.ClientOptions{
+		BaseURL: suite.server.URL,
+	}).SetLogLevel(snorlax.TraceLevel)
+
+	_, err := client.Get(context.TODO(), "/pokemon", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(snorlax.NopLogger(), client.Logger())
+}
+
+func TestLoggerTestSuite(t *testing.T) {
+	suite.Run(t, new(LoggerTestSuite))
+}
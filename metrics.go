@@ -1,15 +1,310 @@
 package snorlax
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 
-func init() {
-	prometheus.MustRegister(latencyHist)
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsOptions configures how a Client exports Prometheus metrics.
+type MetricsOptions struct {
+	// Registerer is where the metrics are registered. It defaults to
+	// prometheus.DefaultRegisterer. Clients which register identical metrics
+	// with the same Registerer share them.
+	Registerer prometheus.Registerer
+
+	// Namespace prefixes the name of every metric. It defaults to "snorlax".
+	Namespace string
+
+	// ConstLabels are added to every metric, for example to tell apart the
+	// metrics of clients calling different upstreams.
+	ConstLabels prometheus.Labels
+}
+
+// sizeBuckets are the histogram buckets, in bytes, used for body sizes.
+var sizeBuckets = prometheus.ExponentialBuckets(64, 4, 8)
+
+// metrics holds the collectors a client records its requests with. All of
+// its methods are safe to call on a nil *metrics, in which case they do
+// nothing.
+type metrics struct {
+	latency       *prometheus.HistogramVec
+	inFlight      prometheus.Gauge
+	requestSize   *prometheus.HistogramVec
+	responseSize  *prometheus.HistogramVec
+	errors        *prometheus.CounterVec
+	retries       *prometheus.CounterVec
+	rateLimitWait prometheus.Histogram
+	circuitState  *prometheus.GaugeVec
+}
+
+// newMetrics constructs the collectors described by opts, and registers them.
+// Collectors which are already registered are reused. It returns an error if
+// any collector couldn't be registered, along with usable, but possibly
+// unregistered, metrics.
+func newMetrics(opts *MetricsOptions) (*metrics, error) {
+	if opts == nil {
+		opts = &MetricsOptions{}
+	}
+
+	reg, ns := opts.Registerer, opts.Namespace
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	if ns == "" {
+		ns = "snorlax"
+	}
+
+	m := metrics{
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   ns,
+			Subsystem:   "requests",
+			Name:        "latency",
+			Help:        "Request latency in seconds",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"method", "code", "path"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   ns,
+			Subsystem:   "requests",
+			Name:        "in_flight",
+			Help:        "Number of requests currently in flight",
+			ConstLabels: opts.ConstLabels,
+		}),
+		requestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   ns,
+			Subsystem:   "requests",
+			Name:        "request_size_bytes",
+			Help:        "Request body size in bytes",
+			ConstLabels: opts.ConstLabels,
+			Buckets:     sizeBuckets,
+		}, []string{"method", "path"}),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   ns,
+			Subsystem:   "requests",
+			Name:        "response_size_bytes",
+			Help:        "Response body size in bytes",
+			ConstLabels: opts.ConstLabels,
+			Buckets:     sizeBuckets,
+		}, []string{"method", "code", "path"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   ns,
+			Subsystem:   "requests",
+			Name:        "errors_total",
+			Help:        "Number of failed requests by error class",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"method", "class"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   ns,
+			Subsystem:   "requests",
+			Name:        "retries_total",
+			Help:        "Number of request retries",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"method"}),
+		rateLimitWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   ns,
+			Subsystem:   "ratelimiter",
+			Name:        "wait_seconds",
+			Help:        "Time requests waited for the rate limiter in seconds",
+			ConstLabels: opts.ConstLabels,
+		}),
+		circuitState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   ns,
+			Subsystem:   "circuitbreaker",
+			Name:        "state",
+			Help:        "Circuit breaker state (0 closed, 1 half-open, 2 open)",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"key"}),
+	}
+
+	var errs []string
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	var err error
+	m.latency, err = register(reg, m.latency)
+	check(err)
+	m.inFlight, err = register(reg, m.inFlight)
+	check(err)
+	m.requestSize, err = register(reg, m.requestSize)
+	check(err)
+	m.responseSize, err = register(reg, m.responseSize)
+	check(err)
+	m.errors, err = register(reg, m.errors)
+	check(err)
+	m.retries, err = register(reg, m.retries)
+	check(err)
+	m.rateLimitWait, err = register(reg, m.rateLimitWait)
+	check(err)
+	m.circuitState, err = register(reg, m.circuitState)
+	check(err)
+
+	if len(errs) > 0 {
+		return &m, fmt.Errorf("failed to register metrics: %s",
+			strings.Join(errs, "; "))
+	}
+
+	return &m, nil
+}
+
+// register registers the collector, returning the existing collector instead
+// if an identical one is already registered.
+func register[C prometheus.Collector](reg prometheus.Registerer, c C) (C,
+	error) {
+	err := reg.Register(c)
+
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(C); ok {
+			return existing, nil
+		}
+	}
+
+	return c, err
+}
+
+// observeRequest records the latency and body sizes of a completed request.
+func (m *metrics) observeRequest(req *http.Request, res *http.Response,
+	path string, latency float64) {
+	if m == nil {
+		return
+	}
+
+	code := strconv.Itoa(res.StatusCode)
+	m.latency.WithLabelValues(req.Method, code, path).Observe(latency)
+
+	if req.ContentLength > 0 {
+		m.requestSize.WithLabelValues(req.Method, path).
+			Observe(float64(req.ContentLength))
+	}
+
+	// The response body hasn't been read yet, so its size is observed once
+	// the caller has finished with it.
+	if res.Body != nil && res.Body != http.NoBody {
+		res.Body = &countingBody{ReadCloser: res.Body, observe: func(n int64) {
+			m.responseSize.WithLabelValues(req.Method, code, path).
+				Observe(float64(n))
+		}}
+	}
+}
+
+// observeError counts a failed request by the class of its error.
+func (m *metrics) observeError(method string, err error) {
+	if m == nil {
+		return
+	}
+
+	m.errors.WithLabelValues(method, errorClass(err)).Inc()
+}
+
+// observeRetry counts a retried request.
+func (m *metrics) observeRetry(method string) {
+	if m == nil {
+		return
+	}
+
+	m.retries.WithLabelValues(method).Inc()
+}
+
+// observeRateLimitWait records the time a request waited for the rate
+// limiter.
+func (m *metrics) observeRateLimitWait(seconds float64) {
+	if m == nil {
+		return
+	}
+
+	m.rateLimitWait.Observe(seconds)
+}
+
+// setCircuitState records the state of a circuit breaker circuit.
+func (m *metrics) setCircuitState(key string, state CircuitState) {
+	if m == nil {
+		return
+	}
+
+	m.circuitState.WithLabelValues(key).Set(float64(state))
+}
+
+// trackInFlight marks a request as in flight, returning a function which marks
+// it as complete.
+func (m *metrics) trackInFlight() func() {
+	if m == nil {
+		return func() {}
+	}
+
+	m.inFlight.Inc()
+	return m.inFlight.Dec
+}
+
+// errorClass classifies a request error for the errors metric.
+func errorClass(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var certErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &recordErr), errors.As(err, &authorityErr),
+		errors.As(err, &certErr), errors.As(err, &hostnameErr),
+		strings.Contains(err.Error(), "tls: "):
+		return "tls"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "reset"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "refused"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	}
+
+	var limitErr *RateLimitedError
+	if errors.As(err, &limitErr) {
+		return "rate_limited"
+	}
+
+	return "other"
+}
+
+// countingBody wraps a response body, reporting the number of bytes read from
+// it when it is closed.
+type countingBody struct {
+	io.ReadCloser
+	n       int64
+	once    sync.Once
+	observe func(n int64)
 }
 
-// latencyHist measures each request's latency.
-var latencyHist = prometheus.NewHistogramVec(prometheus.HistogramOpts{
-	Namespace: "snorlax",
-	Subsystem: "requests",
-	Name:      "latency",
-	Help:      "Request latency in seconds",
-}, []string{"method", "code", "path"})
+// Read satisfies the io.Reader interface.
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// Close satisfies the io.Closer interface.
+func (b *countingBody) Close() error {
+	b.once.Do(func() { b.observe(b.n) })
+	return b.ReadCloser.Close()
+}
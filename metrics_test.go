@@ -0,0 +1,127 @@
+package snorlax_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/suite"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+	registry *prometheus.Registry
+	server   *httptest.Server
+}
+
+func (suite *MetricsTestSuite) SetupTest() {
+	suite.registry = prometheus.NewRegistry()
+	suite.server = httptest.NewServer(http.HandlerFunc(EchoHandler))
+}
+
+func (suite *MetricsTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+// client returns a client recording metrics with the suite's registry.
+func (suite *MetricsTestSuite) client(baseURL string) snorlax.Client {
+	return snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(baseURL).
+		SetMetrics(&snorlax.MetricsOptions{
+			Registerer:  suite.registry,
+			Namespace:   "pokedex",
+			ConstLabels: prometheus.Labels{"client": "snorlax"},
+		})
+}
+
+// family returns the gathered metric family with the name.
+func (suite *MetricsTestSuite) family(name string) *dto.MetricFamily {
+	families, err := suite.registry.Gather()
+	suite.Require().NoError(err)
+
+	for _, family := range families {
+		if family.GetName() == name {
+			return family
+		}
+	}
+
+	suite.FailNow("metric family not found", name)
+	return nil
+}
+
+// label returns the value of the metric's label with the name.
+func label(metric *dto.Metric, name string) string {
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+
+	return ""
+}
+
+func (suite *MetricsTestSuite) TestMetrics_Request() {
+	client := suite.client(suite.server.URL)
+
+	res, err := client.Post(context.TODO(), "/pokemon", nil,
+		bytes.NewBufferString("snorlax"))
+	suite.Require().NoError(err)
+
+	_, err = ioutil.ReadAll(res.Body)
+	suite.Require().NoError(err)
+	suite.Require().NoError(res.Body.Close())
+
+	latency := suite.family("pokedex_requests_latency").GetMetric()
+	suite.Require().Len(latency, 1)
+	suite.Require().Equal("snorlax", label(latency[0], "client"))
+	suite.Require().Equal("POST", label(latency[0], "method"))
+	suite.Require().Equal("200", label(latency[0], "code"))
+	suite.Require().EqualValues(1, latency[0].GetHistogram().GetSampleCount())
+
+	requestSize := suite.family("pokedex_requests_request_size_bytes").
+		GetMetric()
+	suite.Require().EqualValues(7, requestSize[0].GetHistogram().GetSampleSum())
+
+	responseSize := suite.family("pokedex_requests_response_size_bytes").
+		GetMetric()
+	suite.Require().EqualValues(7,
+		responseSize[0].GetHistogram().GetSampleSum())
+
+	inFlight := suite.family("pokedex_requests_in_flight").GetMetric()
+	suite.Require().EqualValues(0, inFlight[0].GetGauge().GetValue())
+}
+
+func (suite *MetricsTestSuite) TestMetrics_Errors() {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	_, err := suite.client(server.URL).Get(context.TODO(), "/pokemon", nil)
+	suite.Require().Error(err)
+
+	errs := suite.family("pokedex_requests_errors_total").GetMetric()
+	suite.Require().Len(errs, 1)
+	suite.Require().Equal("refused", label(errs[0], "class"))
+	suite.Require().EqualValues(1, errs[0].GetCounter().GetValue())
+}
+
+func (suite *MetricsTestSuite) TestMetrics_SharedRegistry() {
+	for i := 0; i < 2; i++ {
+		_, err := suite.client(suite.server.URL).Get(context.TODO(),
+			"/pokemon", nil)
+		suite.Require().NoError(err)
+	}
+
+	latency := suite.family("pokedex_requests_latency").GetMetric()
+	suite.Require().Len(latency, 1)
+	suite.Require().EqualValues(2, latency[0].GetHistogram().GetSampleCount())
+}
+
+func TestMetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
@@ -0,0 +1,32 @@
+package snorlax
+
+import "net/http"
+
+// Doer sends an HTTP request and returns its response. *http.Client satisfies
+// the Doer interface.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc is an adapter which allows ordinary functions to be used as Doers.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps a Doer with additional behaviour, such as logging, caching
+// or error mapping. A Middleware decides whether, and how many times, the next
+// Doer in the chain is called.
+type Middleware func(next Doer) Doer
+
+// chain wraps d with the middleware so that the first middleware is the
+// outermost layer.
+func chain(d Doer, middleware ...Middleware) Doer {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		d = middleware[i](d)
+	}
+
+	return d
+}
@@ -0,0 +1,94 @@
+package snorlax_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/stretchr/testify/suite"
+)
+
+type MiddlewareTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+func (suite *MiddlewareTestSuite) SetupSuite() {
+	suite.server = httptest.NewServer(http.HandlerFunc(EchoHandler))
+}
+
+func (suite *MiddlewareTestSuite) TearDownSuite() {
+	suite.server.Close()
+}
+
+// recorder returns Middleware which appends its name to calls on the way in
+// and on the way out of the chain.
+func recorder(name string, calls *[]string) snorlax.Middleware {
+	return func(next snorlax.Doer) snorlax.Doer {
+		return snorlax.DoerFunc(func(r *http.Request) (*http.Response,
+			error) {
+			*calls = append(*calls, name+" in")
+			res, err := next.Do(r)
+			*calls = append(*calls, name+" out")
+			return res, err
+		})
+	}
+}
+
+func (suite *MiddlewareTestSuite) TestMiddleware_Order() {
+	var calls []string
+
+	client := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		AddMiddleware(recorder("first", &calls), recorder("second", &calls))
+
+	res, err := client.Get(context.TODO(), "/middleware", nil,
+		snorlax.WithMiddleware(recorder("request", &calls)))
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, res.StatusCode)
+	suite.Require().Equal([]string{
+		"first in", "second in", "request in",
+		"request out", "second out", "first out",
+	}, calls)
+}
+
+func (suite *MiddlewareTestSuite) TestResponseHook() {
+	var calls []string
+	hook := func(name string) snorlax.ResponseHook {
+		return func(c snorlax.Client, r *snorlax.Response) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+
+	client := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		AddResponseHooks(hook("client"))
+
+	res, err := client.Get(context.TODO(), "/hooks", nil,
+		snorlax.WithResponseHook(hook("request")))
+	suite.Require().NoError(err)
+	suite.Require().NotNil(res)
+	suite.Require().Equal([]string{"client", "request"}, calls)
+}
+
+func (suite *MiddlewareTestSuite) TestResponseHook_Error() {
+	errTeapot := errors.New("i'm a teapot")
+
+	client := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		AddResponseHook(func(c snorlax.Client, r *snorlax.Response) error {
+			return errTeapot
+		})
+
+	res, err := client.Get(context.TODO(), "/hooks", nil)
+	suite.Require().Nil(res)
+	suite.Require().True(errors.Is(err, errTeapot))
+}
+
+func TestMiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(MiddlewareTestSuite))
+}
@@ -0,0 +1,324 @@
+package snorlax
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Multipart builds a multipart/form-data request body from fields and files.
+// The body is streamed as it is sent, so files are never held in memory.
+//
+//	form := snorlax.NewMultipart().
+//		Field("name", "snorlax").
+//		File("sprite", "snorlax.png")
+//
+//	res, err := client.Post(ctx, "/pokemon", nil, nil,
+//		snorlax.WithMultipart(form))
+type Multipart struct {
+	parts    []multipartPart
+	boundary string
+	progress func(sent, total int64)
+}
+
+// multipartPart is a field or file in a Multipart body.
+type multipartPart struct {
+	name        string
+	filename    string
+	contentType string
+
+	// Exactly one of value, path and reader is the part's content.
+	value  string
+	path   string
+	reader io.Reader
+}
+
+// NewMultipart constructs an empty Multipart body.
+func NewMultipart() *Multipart {
+	return &Multipart{boundary: multipart.NewWriter(nil).Boundary()}
+}
+
+// Field adds a form field with the value.
+func (m *Multipart) Field(name, value string) *Multipart {
+	m.parts = append(m.parts, multipartPart{name: name, value: value})
+	return m
+}
+
+// File adds the file at path, which is opened when the body is sent. The file
+// is named after the last element of path, and its content type is detected
+// from its extension.
+func (m *Multipart) File(name, path string) *Multipart {
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	m.parts = append(m.parts, multipartPart{
+		name:        name,
+		filename:    filepath.Base(path),
+		contentType: contentType,
+		path:        path,
+	})
+	return m
+}
+
+// Reader adds a file named filename with the content type, whose content is
+// read from r as the body is sent. An empty content type is sent as
+// application/octet-stream.
+//
+// The content can only be read once, so retried requests must buffer the
+// whole body in memory. Add files using File to avoid this.
+func (m *Multipart) Reader(name, filename, contentType string,
+	r io.Reader) *Multipart {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	m.parts = append(m.parts, multipartPart{
+		name:        name,
+		filename:    filename,
+		contentType: contentType,
+		reader:      r,
+	})
+	return m
+}
+
+// OnProgress sets a callback which is called as the body is sent, with the
+// number of bytes sent so far and the total size of the body, or -1 if the
+// size isn't known. It is called on the goroutine sending the request, and
+// starts from zero again if the request is retried.
+func (m *Multipart) OnProgress(fn func(sent, total int64)) *Multipart {
+	m.progress = fn
+	return m
+}
+
+// ContentType returns the body's Content-Type, including its boundary.
+func (m *Multipart) ContentType() string {
+	return "multipart/form-data; boundary=" + m.boundary
+}
+
+// WithMultipart sends the Multipart as the request's body, and sets the
+// request's Content-Type. The body passed to the request is replaced.
+func WithMultipart(m *Multipart) RequestHook {
+	return func(c Client, r *http.Request) error {
+		size, err := m.size()
+		if err != nil {
+			return err
+		}
+
+		if r.Body != nil {
+			r.Body.Close()
+		}
+
+		r.Header.Set("Content-Type", m.ContentType())
+		r.ContentLength = size
+		r.Body = m.body(size)
+		r.GetBody = nil
+
+		for _, p := range m.parts {
+			if p.reader != nil {
+				return nil
+			}
+		}
+
+		r.GetBody = func() (io.ReadCloser, error) {
+			return m.body(size), nil
+		}
+		return nil
+	}
+}
+
+// size returns the length of the encoded body, or -1 if the length of any of
+// its readers isn't known.
+func (m *Multipart) size() (int64, error) {
+	var counter countingWriter
+	w := multipart.NewWriter(&counter)
+	if err := w.SetBoundary(m.boundary); err != nil {
+		return 0, fmt.Errorf("failed to set multipart boundary: %w", err)
+	}
+
+	var content int64
+	known := true
+	for _, p := range m.parts {
+		if _, err := w.CreatePart(p.header()); err != nil {
+			return 0, err
+		}
+
+		switch {
+		case p.path != "":
+			info, err := os.Stat(p.path)
+			if err != nil {
+				return 0, fmt.Errorf("failed to stat multipart file: %w", err)
+			}
+			content += info.Size()
+
+		case p.reader != nil:
+			n, ok := readerSize(p.reader)
+			if !ok {
+				known = false
+			}
+			content += n
+
+		default:
+			content += int64(len(p.value))
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return 0, err
+	}
+
+	if !known {
+		return -1, nil
+	}
+
+	return counter.n + content, nil
+}
+
+// body returns a reader which streams the encoded body through a pipe. The
+// body isn't encoded until it is first read, so that a body which is never
+// sent doesn't leak the goroutine encoding it.
+func (m *Multipart) body(size int64) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	return &multipartBody{
+		pipe:     pr,
+		total:    size,
+		progress: m.progress,
+		start: func() {
+			go func() {
+				pw.CloseWithError(m.write(pw))
+			}()
+		},
+	}
+}
+
+// write encodes the body to w.
+func (m *Multipart) write(w io.Writer) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(m.boundary); err != nil {
+		return fmt.Errorf("failed to set multipart boundary: %w", err)
+	}
+
+	for _, p := range m.parts {
+		part, err := mw.CreatePart(p.header())
+		if err != nil {
+			return err
+		}
+
+		if err = p.copy(part); err != nil {
+			return err
+		}
+	}
+
+	return mw.Close()
+}
+
+// header returns the headers of the part.
+func (p multipartPart) header() textproto.MIMEHeader {
+	h := make(textproto.MIMEHeader)
+	disposition := "form-data; name=" + quote(p.name)
+	if p.filename != "" {
+		disposition += "; filename=" + quote(p.filename)
+	}
+	h.Set("Content-Disposition", disposition)
+
+	if p.contentType != "" {
+		h.Set("Content-Type", p.contentType)
+	}
+
+	return h
+}
+
+// copy writes the content of the part to w.
+func (p multipartPart) copy(w io.Writer) error {
+	switch {
+	case p.path != "":
+		f, err := os.Open(p.path)
+		if err != nil {
+			return fmt.Errorf("failed to open multipart file: %w", err)
+		}
+		defer f.Close()
+
+		_, err = io.Copy(w, f)
+		return err
+
+	case p.reader != nil:
+		_, err := io.Copy(w, p.reader)
+		return err
+	}
+
+	_, err := io.WriteString(w, p.value)
+	return err
+}
+
+// multipartBody is a request body streamed from a pipe, which reports its
+// progress.
+type multipartBody struct {
+	pipe     *io.PipeReader
+	once     sync.Once
+	start    func()
+	sent     int64
+	total    int64
+	progress func(sent, total int64)
+}
+
+// Read satisfies the io.Reader interface.
+func (b *multipartBody) Read(p []byte) (int, error) {
+	b.once.Do(b.start)
+
+	n, err := b.pipe.Read(p)
+	if n > 0 {
+		b.sent += int64(n)
+		if b.progress != nil {
+			b.progress(b.sent, b.total)
+		}
+	}
+
+	return n, err
+}
+
+// Close satisfies the io.Closer interface. It stops the body from being
+// encoded any further.
+func (b *multipartBody) Close() error {
+	return b.pipe.Close()
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter struct {
+	n int64
+}
+
+// Write satisfies the io.Writer interface.
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// readerSize returns the number of bytes left in r, if it can be known without
+// reading it.
+func readerSize(r io.Reader) (int64, bool) {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len()), true
+	case *os.File:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0, false
+		}
+
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		return info.Size() - offset, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,156 @@
+package snorlax_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/stretchr/testify/suite"
+)
+
+type MultipartTestSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	client   snorlax.Client
+	requests []*http.Request
+	files    map[string]string
+	sprite   string
+}
+
+func (suite *MultipartTestSuite) SetupTest() {
+	suite.requests = nil
+	suite.files = make(map[string]string)
+	suite.server = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			suite.requests = append(suite.requests, r)
+
+			for name, headers := range r.MultipartForm.File {
+				f, err := headers[0].Open()
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				content, _ := ioutil.ReadAll(f)
+				f.Close()
+				suite.files[name] = headers[0].Filename + ";" +
+					headers[0].Header.Get("Content-Type") + ";" +
+					string(content)
+			}
+
+			// Fail the first attempt, to check the body is sent again.
+			if r.URL.Path == "/flaky" && len(suite.requests) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			w.Write([]byte(r.FormValue("name")))
+		}))
+
+	suite.client = snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL)
+
+	suite.sprite = filepath.Join(suite.T().TempDir(), "snorlax.png")
+	suite.Require().NoError(ioutil.WriteFile(suite.sprite, []byte("zzz"),
+		0644))
+}
+
+func (suite *MultipartTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *MultipartTestSuite) TestUpload() {
+	var sent, total int64
+	form := snorlax.NewMultipart().
+		Field("name", "snorlax").
+		File("sprite", suite.sprite).
+		Reader("cry", `cry "1".ogg`, "audio/ogg",
+			strings.NewReader("yawn")).
+		OnProgress(func(s, t int64) {
+			sent, total = s, t
+		})
+
+	res, err := suite.client.Post(context.TODO(), "/pokemon", nil, nil,
+		snorlax.WithMultipart(form))
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, res.StatusCode)
+
+	body, err := ioutil.ReadAll(res.Body)
+	suite.Require().NoError(err)
+	suite.Require().Equal("snorlax", string(body))
+
+	suite.Require().Equal("snorlax.png;image/png;zzz", suite.files["sprite"])
+	suite.Require().Equal(`cry "1".ogg;audio/ogg;yawn`, suite.files["cry"])
+
+	req := suite.requests[0]
+	suite.Require().Equal(form.ContentType(), req.Header.Get("Content-Type"))
+	suite.Require().Greater(req.ContentLength, int64(0))
+	suite.Require().Equal(req.ContentLength, total)
+	suite.Require().Equal(total, sent)
+}
+
+func (suite *MultipartTestSuite) TestUnknownSize() {
+	var total int64
+	form := snorlax.NewMultipart().
+		Reader("cry", "cry.ogg", "", io.LimitReader(
+			strings.NewReader("yawn"), 4)).
+		OnProgress(func(_, t int64) {
+			total = t
+		})
+
+	_, err := suite.client.Post(context.TODO(), "/pokemon", nil, nil,
+		snorlax.WithMultipart(form))
+	suite.Require().NoError(err)
+
+	// The body is sent in chunks.
+	suite.Require().Equal(int64(-1), suite.requests[0].ContentLength)
+	suite.Require().Equal([]string{"chunked"},
+		suite.requests[0].TransferEncoding)
+	suite.Require().Equal(int64(-1), total)
+	suite.Require().Equal("cry.ogg;application/octet-stream;yawn",
+		suite.files["cry"])
+}
+
+func (suite *MultipartTestSuite) TestRetry() {
+	policy := snorlax.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+
+	form := snorlax.NewMultipart().
+		Field("name", "snorlax").
+		File("sprite", suite.sprite)
+
+	res, err := suite.client.Post(context.TODO(), "/flaky", nil, nil,
+		snorlax.WithMultipart(form), snorlax.WithRetryPolicy(policy))
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, res.StatusCode)
+
+	suite.Require().Len(suite.requests, 2)
+	suite.Require().Equal("snorlax.png;image/png;zzz", suite.files["sprite"])
+}
+
+func (suite *MultipartTestSuite) TestMissingFile() {
+	form := snorlax.NewMultipart().
+		File("sprite", filepath.Join(suite.T().TempDir(), "missing.png"))
+
+	_, err := suite.client.Post(context.TODO(), "/pokemon", nil, nil,
+		snorlax.WithMultipart(form))
+	suite.Require().True(errors.Is(err, os.ErrNotExist))
+	suite.Require().Empty(suite.requests)
+}
+
+func TestMultipartTestSuite(t *testing.T) {
+	suite.Run(t, new(MultipartTestSuite))
+}
@@ -0,0 +1,350 @@
+package snorlax
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuth2Token is an access token issued by an OAuth2 authorization server.
+type OAuth2Token struct {
+	AccessToken  string
+	TokenType    string
+	RefreshToken string
+
+	// Expiry is when the access token expires. A zero Expiry means the token
+	// does not expire.
+	Expiry time.Time
+}
+
+// Type returns the token's type, defaulting to Bearer.
+func (t *OAuth2Token) Type() string {
+	if strings.EqualFold(t.TokenType, "bearer") || t.TokenType == "" {
+		return "Bearer"
+	}
+
+	return t.TokenType
+}
+
+// valid returns whether the token can be used at now, treating it as expired
+// skew before its Expiry.
+func (t *OAuth2Token) valid(now time.Time, skew time.Duration) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+
+	return t.Expiry.IsZero() || now.Add(skew).Before(t.Expiry)
+}
+
+// OAuth2Error is returned when an OAuth2 token endpoint rejects a request for a
+// token, as described in RFC 6749 section 5.2.
+type OAuth2Error struct {
+	StatusCode  int
+	Code        string
+	Description string
+	URI         string
+}
+
+// Error satisfies the error interface.
+func (e *OAuth2Error) Error() string {
+	msg := fmt.Sprintf("oauth2: token request failed with status %d",
+		e.StatusCode)
+	if e.Code != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Code)
+	}
+	if e.Description != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Description)
+	}
+
+	return msg
+}
+
+// OAuth2 fetches and caches access tokens from an OAuth2 token endpoint using
+// either the client credentials or the refresh token grant. Tokens are
+// refreshed ExpirySkew before they expire, and concurrent requests share a
+// single refresh. An OAuth2 is safe for concurrent use, but its fields should
+// not be modified after it is first used.
+type OAuth2 struct {
+	// TokenURL is the authorization server's token endpoint.
+	TokenURL string
+
+	// ClientID and ClientSecret authenticate the client with the token
+	// endpoint. They are sent using HTTP basic authentication unless
+	// CredentialsInBody is set.
+	ClientID          string
+	ClientSecret      string
+	CredentialsInBody bool
+
+	// Scopes optionally limits the scope of the access tokens requested.
+	Scopes []string
+
+	// RefreshToken selects the refresh token grant, and is exchanged for
+	// access tokens. If the token endpoint issues a new refresh token then it
+	// replaces this one. If empty, the client credentials grant is used.
+	RefreshToken string
+
+	// EndpointParams are additional parameters sent to the token endpoint,
+	// such as audience.
+	EndpointParams url.Values
+
+	// ExpirySkew is how long before its expiry a token is refreshed.
+	ExpirySkew time.Duration
+
+	// HTTPClient sends requests to the token endpoint. If nil, the
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// Timeout limits how long a request to the token endpoint may take. The
+	// request is shared by concurrent callers, so it isn't cancelled with any
+	// one caller's context. If zero, a timeout of 30 seconds is used.
+	Timeout time.Duration
+
+	mu      sync.Mutex
+	token   *OAuth2Token
+	pending *tokenRequest
+}
+
+// tokenRequest is a request for a token which concurrent callers wait on.
+type tokenRequest struct {
+	done  chan struct{}
+	token *OAuth2Token
+	err   error
+}
+
+// NewOAuth2ClientCredentials returns an OAuth2 using the client credentials
+// grant.
+func NewOAuth2ClientCredentials(tokenURL, clientID, clientSecret string,
+	scopes ...string) *OAuth2 {
+	return &OAuth2{
+		TokenURL:     tokenURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       scopes,
+		ExpirySkew:   10 * time.Second,
+	}
+}
+
+// NewOAuth2RefreshToken returns an OAuth2 using the refresh token grant.
+func NewOAuth2RefreshToken(tokenURL, clientID, clientSecret,
+	refreshToken string) *OAuth2 {
+	return &OAuth2{
+		TokenURL:     tokenURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RefreshToken: refreshToken,
+		ExpirySkew:   10 * time.Second,
+	}
+}
+
+// Token returns a valid access token, requesting a new one from the token
+// endpoint if the cached token has expired.
+func (o *OAuth2) Token(ctx context.Context) (*OAuth2Token, error) {
+	return o.fetch(ctx, "")
+}
+
+// fetch returns the cached token if it is valid and isn't stale, otherwise it
+// requests a new one. Only one request is made at a time; concurrent callers
+// wait for its result until their own context is done.
+func (o *OAuth2) fetch(ctx context.Context, stale string) (*OAuth2Token,
+	error) {
+	o.mu.Lock()
+	if t := o.token; t.valid(time.Now(), o.ExpirySkew) &&
+		(stale == "" || t.AccessToken != stale) {
+		o.mu.Unlock()
+		return t, nil
+	}
+
+	p := o.pending
+	if p == nil {
+		p = &tokenRequest{done: make(chan struct{})}
+		o.pending = p
+		go o.refresh(p, o.RefreshToken)
+	}
+	o.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return p.token, p.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refresh requests a new token for p, and caches it. The request has its own
+// timeout rather than the context of the caller which started it, so that the
+// caller giving up doesn't fail the others waiting on p.
+func (o *OAuth2) refresh(p *tokenRequest, refreshToken string) {
+	timeout := o.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	p.token, p.err = o.exchange(ctx, refreshToken)
+
+	o.mu.Lock()
+	if p.err == nil {
+		o.token = p.token
+		if p.token.RefreshToken != "" && o.RefreshToken != "" {
+			o.RefreshToken = p.token.RefreshToken
+		}
+	}
+	o.pending = nil
+	o.mu.Unlock()
+	close(p.done)
+}
+
+// exchange requests a new token from the token endpoint.
+func (o *OAuth2) exchange(ctx context.Context, refreshToken string) (
+	*OAuth2Token, error) {
+	form := url.Values{}
+	for k, v := range o.EndpointParams {
+		form[k] = append([]string(nil), v...)
+	}
+
+	if refreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", refreshToken)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+
+	if len(o.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.Scopes, " "))
+	}
+
+	if o.CredentialsInBody {
+		form.Set("client_id", o.ClientID)
+		if o.ClientSecret != "" {
+			form.Set("client_secret", o.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.TokenURL,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !o.CredentialsInBody {
+		req.SetBasicAuth(url.QueryEscape(o.ClientID),
+			url.QueryEscape(o.ClientSecret))
+	}
+
+	hc := o.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+
+	res, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request token: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var data struct {
+		AccessToken      string      `json:"access_token"`
+		TokenType        string      `json:"token_type"`
+		RefreshToken     string      `json:"refresh_token"`
+		ExpiresIn        json.Number `json:"expires_in"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+		ErrorURI         string      `json:"error_uri"`
+	}
+	jsonErr := json.Unmarshal(body, &data)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 || data.Error != "" {
+		return nil, &OAuth2Error{
+			StatusCode:  res.StatusCode,
+			Code:        data.Error,
+			Description: data.ErrorDescription,
+			URI:         data.ErrorURI,
+		}
+	}
+
+	if jsonErr != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", jsonErr)
+	}
+
+	if data.AccessToken == "" {
+		return nil, errors.New("token response is missing access_token")
+	}
+
+	token := OAuth2Token{
+		AccessToken:  data.AccessToken,
+		TokenType:    data.TokenType,
+		RefreshToken: data.RefreshToken,
+	}
+
+	if expiresIn, err := data.ExpiresIn.Int64(); err == nil && expiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	}
+
+	return &token, nil
+}
+
+// WithOAuth2 authenticates requests using access tokens fetched by o. If a
+// request is rejected with 401 Unauthorized it is retried once with a freshly
+// requested token, provided its body can be sent again. Add it to a client
+// using AddRequestHook to authenticate all of its requests.
+func WithOAuth2(o *OAuth2) RequestHook {
+	return func(c Client, r *http.Request) error {
+		token, err := o.Token(r.Context())
+		if err != nil {
+			return fmt.Errorf("failed to get oauth2 token: %w", err)
+		}
+
+		r.Header.Set("Authorization", token.Type()+" "+token.AccessToken)
+
+		return WithMiddleware(o.retryUnauthorized(token))(c, r)
+	}
+}
+
+// retryUnauthorized returns Middleware which retries a request rejected with
+// 401 Unauthorized once, using a new token in place of token.
+func (o *OAuth2) retryUnauthorized(token *OAuth2Token) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			res, err := next.Do(req)
+			if err != nil || res.StatusCode != http.StatusUnauthorized {
+				return res, err
+			}
+
+			if !rewindable(req) {
+				return res, nil
+			}
+
+			refreshed, err := o.fetch(req.Context(), token.AccessToken)
+			if err != nil {
+				return res, nil
+			}
+
+			retry, err := rewind(req)
+			if err != nil {
+				return res, nil
+			}
+			retry.Header.Set("Authorization",
+				refreshed.Type()+" "+refreshed.AccessToken)
+			discard(res)
+
+			return next.Do(retry)
+		})
+	}
+}
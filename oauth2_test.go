@@ -0,0 +1,257 @@
+package snorlax_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/stretchr/testify/suite"
+)
+
+type OAuth2TestSuite struct {
+	suite.Suite
+	api       *httptest.Server
+	apiCalls  int32
+	issued    int32
+	expiresIn int
+	forms     chan map[string]string
+	release   chan struct{}
+	tokens    *httptest.Server
+}
+
+func (suite *OAuth2TestSuite) SetupTest() {
+	atomic.StoreInt32(&suite.apiCalls, 0)
+	atomic.StoreInt32(&suite.issued, 0)
+	suite.expiresIn = 3600
+	suite.forms = make(chan map[string]string, 100)
+	suite.release = nil
+
+	// The token endpoint issues access tokens t1, t2, ... and refresh tokens
+	// r1, r2, ...
+	suite.tokens = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			user, pass, _ := r.BasicAuth()
+			suite.forms <- map[string]string{
+				"grant_type":    r.PostForm.Get("grant_type"),
+				"refresh_token": r.PostForm.Get("refresh_token"),
+				"scope":         r.PostForm.Get("scope"),
+				"basic":         user + ":" + pass,
+			}
+
+			if pass != "s3cr3t" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"invalid_client",` +
+					`"error_description":"bad secret"}`))
+				return
+			}
+
+			time.Sleep(10 * time.Millisecond)
+			if suite.release != nil {
+				<-suite.release
+			}
+
+			n := atomic.AddInt32(&suite.issued, 1)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token":  fmt.Sprintf("t%d", n),
+				"token_type":    "bearer",
+				"refresh_token": fmt.Sprintf("r%d", n),
+				"expires_in":    suite.expiresIn,
+			})
+		}))
+
+	// The API only accepts the most recently issued token, and echoes the
+	// request body. Requests to /locked are always rejected.
+	suite.api = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&suite.apiCalls, 1)
+			want := fmt.Sprintf("Bearer t%d", atomic.LoadInt32(&suite.issued))
+			if r.Header.Get("Authorization") != want ||
+				r.URL.Path == "/locked" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			EchoHandler(w, r)
+		}))
+}
+
+func (suite *OAuth2TestSuite) TearDownTest() {
+	suite.api.Close()
+	suite.tokens.Close()
+}
+
+func (suite *OAuth2TestSuite) client(o *snorlax.OAuth2) snorlax.Client {
+	return snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.api.URL).
+		AddRequestHook(snorlax.WithOAuth2(o))
+}
+
+func (suite *OAuth2TestSuite) TestClientCredentials() {
+	o := snorlax.NewOAuth2ClientCredentials(suite.tokens.URL, "snorlax",
+		"s3cr3t", "read", "write")
+	client := suite.client(o)
+
+	for i := 0; i < 3; i++ {
+		res, err := client.Get(context.TODO(), "/pokemon", nil)
+		suite.Require().NoError(err)
+		suite.Require().Equal(http.StatusOK, res.StatusCode)
+	}
+
+	suite.Require().Equal(int32(1), atomic.LoadInt32(&suite.issued))
+	form := <-suite.forms
+	suite.Require().Equal("client_credentials", form["grant_type"])
+	suite.Require().Equal("read write", form["scope"])
+	suite.Require().Equal("snorlax:s3cr3t", form["basic"])
+}
+
+func (suite *OAuth2TestSuite) TestExpirySkew() {
+	suite.expiresIn = 5
+
+	o := snorlax.NewOAuth2ClientCredentials(suite.tokens.URL, "snorlax",
+		"s3cr3t")
+	o.ExpirySkew = 10 * time.Second
+
+	for i := 1; i <= 3; i++ {
+		token, err := o.Token(context.TODO())
+		suite.Require().NoError(err)
+		suite.Require().Equal(fmt.Sprintf("t%d", i), token.AccessToken)
+	}
+}
+
+func (suite *OAuth2TestSuite) TestSingleFlight() {
+	o := snorlax.NewOAuth2ClientCredentials(suite.tokens.URL, "snorlax",
+		"s3cr3t")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			token, err := o.Token(context.TODO())
+			suite.NoError(err)
+			suite.Equal("t1", token.AccessToken)
+		}()
+	}
+	wg.Wait()
+
+	suite.Require().Equal(int32(1), atomic.LoadInt32(&suite.issued))
+}
+
+func (suite *OAuth2TestSuite) TestSingleFlight_CallerCancelled() {
+	suite.release = make(chan struct{})
+
+	o := snorlax.NewOAuth2ClientCredentials(suite.tokens.URL, "snorlax",
+		"s3cr3t")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := o.Token(ctx)
+		first <- err
+	}()
+	<-suite.forms
+
+	second := make(chan *snorlax.OAuth2Token, 1)
+	go func() {
+		token, err := o.Token(context.TODO())
+		suite.NoError(err)
+		second <- token
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// The caller which started the request gives up, but the request carries
+	// on for the other caller.
+	cancel()
+	suite.Require().True(errors.Is(<-first, context.Canceled))
+
+	close(suite.release)
+	token := <-second
+	suite.Require().NotNil(token)
+	suite.Require().Equal("t1", token.AccessToken)
+	suite.Require().Equal(int32(1), atomic.LoadInt32(&suite.issued))
+}
+
+func (suite *OAuth2TestSuite) TestRetryUnauthorized() {
+	o := snorlax.NewOAuth2ClientCredentials(suite.tokens.URL, "snorlax",
+		"s3cr3t")
+	client := suite.client(o)
+
+	_, err := o.Token(context.TODO())
+	suite.Require().NoError(err)
+
+	// Revoke t1 by issuing a token the client doesn't know about.
+	atomic.AddInt32(&suite.issued, 1)
+
+	res, err := client.Post(context.TODO(), "/pokemon", nil,
+		strings.NewReader("snorlax"))
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, res.StatusCode)
+
+	body, err := res.RawBody()
+	suite.Require().NoError(err)
+	suite.Require().Equal("snorlax", fmt.Sprint(body))
+	suite.Require().Equal(int32(2), atomic.LoadInt32(&suite.apiCalls))
+}
+
+func (suite *OAuth2TestSuite) TestRetryUnauthorized_Once() {
+	o := snorlax.NewOAuth2ClientCredentials(suite.tokens.URL, "snorlax",
+		"s3cr3t")
+	client := suite.client(o)
+
+	res, err := client.Get(context.TODO(), "/locked", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusUnauthorized, res.StatusCode)
+	suite.Require().Equal(int32(2), atomic.LoadInt32(&suite.apiCalls))
+	suite.Require().Equal(int32(2), atomic.LoadInt32(&suite.issued))
+}
+
+func (suite *OAuth2TestSuite) TestRefreshToken() {
+	o := snorlax.NewOAuth2RefreshToken(suite.tokens.URL, "snorlax", "s3cr3t",
+		"r0")
+	o.ExpirySkew = time.Hour
+
+	for i := 0; i < 2; i++ {
+		_, err := o.Token(context.TODO())
+		suite.Require().NoError(err)
+	}
+
+	// The second refresh uses the refresh token issued by the first.
+	first, second := <-suite.forms, <-suite.forms
+	suite.Require().Equal("refresh_token", first["grant_type"])
+	suite.Require().Equal("r0", first["refresh_token"])
+	suite.Require().Equal("r1", second["refresh_token"])
+}
+
+func (suite *OAuth2TestSuite) TestTokenError() {
+	o := snorlax.NewOAuth2ClientCredentials(suite.tokens.URL, "snorlax",
+		"wrong")
+	client := suite.client(o)
+
+	res, err := client.Get(context.TODO(), "/pokemon", nil)
+	suite.Require().Error(err)
+	suite.Require().Nil(res)
+	suite.Require().Equal(int32(0), atomic.LoadInt32(&suite.apiCalls))
+
+	var oauthErr *snorlax.OAuth2Error
+	suite.Require().True(errors.As(err, &oauthErr))
+	suite.Require().Equal(http.StatusUnauthorized, oauthErr.StatusCode)
+	suite.Require().Equal("invalid_client", oauthErr.Code)
+	suite.Require().Equal("bad secret", oauthErr.Description)
+}
+
+func TestOAuth2TestSuite(t *testing.T) {
+	suite.Run(t, new(OAuth2TestSuite))
+}
@@ -0,0 +1,163 @@
+package snorlax
+
+import (
+	"net/http"
+	"time"
+)
+
+// Setting configures a client derived using With. Each Setting calls one of
+// the Client's setters, so you can write your own in the same way.
+//
+//	func UseTrainer(name string) snorlax.Setting {
+//		return func(c snorlax.Client) {
+//			c.SetHeader("X-Trainer", name)
+//		}
+//	}
+type Setting func(c Client)
+
+// UseBaseURL sets the url that is prepended to all request URLs.
+func UseBaseURL(u string) Setting {
+	return func(c Client) {
+		c.SetBaseURL(u)
+	}
+}
+
+// UseCache sets the Cache which serves GET requests. A nil Cache disables
+// caching.
+func UseCache(cache *Cache) Setting {
+	return func(c Client) {
+		c.SetCache(cache)
+	}
+}
+
+// UseCassette sets the Cassette which records or replays requests. A nil
+// Cassette sends requests as usual.
+func UseCassette(cassette *Cassette) Setting {
+	return func(c Client) {
+		c.SetCassette(cassette)
+	}
+}
+
+// UseCircuitBreaker sets the CircuitBreaker which stops requests from being
+// sent to failing upstreams. A nil CircuitBreaker disables it.
+func UseCircuitBreaker(breaker *CircuitBreaker) Setting {
+	return func(c Client) {
+		c.SetCircuitBreaker(breaker)
+	}
+}
+
+// UseHeader sets a header value to be sent in every request, replacing any
+// existing values associated with the same key.
+func UseHeader(key, value string) Setting {
+	return func(c Client) {
+		c.SetHeader(key, value)
+	}
+}
+
+// UseHTTPClient sets the internal http.Client used to perform requests.
+func UseHTTPClient(hc *http.Client) Setting {
+	return func(c Client) {
+		c.SetHTTPClient(hc)
+	}
+}
+
+// UseHTTPErrors sets whether non-2XX responses are returned as an *HTTPError.
+func UseHTTPErrors(enabled bool) Setting {
+	return func(c Client) {
+		c.SetHTTPErrors(enabled)
+	}
+}
+
+// UseLogger sets the Logger that the client writes its logs to.
+func UseLogger(logger Logger) Setting {
+	return func(c Client) {
+		c.SetLogger(logger)
+	}
+}
+
+// UseLogLevel sets the most verbose level of messages which are logged.
+func UseLogLevel(level Level) Setting {
+	return func(c Client) {
+		c.SetLogLevel(level)
+	}
+}
+
+// UseMetrics enables Prometheus metrics, registering them as configured by
+// opts.
+func UseMetrics(opts *MetricsOptions) Setting {
+	return func(c Client) {
+		c.SetMetrics(opts)
+	}
+}
+
+// UseMiddleware appends Middleware which wraps every request.
+func UseMiddleware(middleware ...Middleware) Setting {
+	return func(c Client) {
+		c.AddMiddleware(middleware...)
+	}
+}
+
+// UsePathNormalizer sets the PathNormalizer which maps requests to the route
+// templates used to label their metrics.
+func UsePathNormalizer(normalizer PathNormalizer) Setting {
+	return func(c Client) {
+		c.SetPathNormalizer(normalizer)
+	}
+}
+
+// UseProxy sets the proxy URL. The proxy is set on a copy of the transport, so
+// the client no longer shares a connection pool with the client it was derived
+// from.
+func UseProxy(u string) Setting {
+	return func(c Client) {
+		c.SetProxy(u)
+	}
+}
+
+// UseRateLimiter sets the RateLimiter which throttles requests. A nil
+// RateLimiter disables client-side rate limiting.
+func UseRateLimiter(limiter *RateLimiter) Setting {
+	return func(c Client) {
+		c.SetRateLimiter(limiter)
+	}
+}
+
+// UseRedactionPolicy sets the policy deciding which values are masked in the
+// client's logs.
+func UseRedactionPolicy(policy *RedactionPolicy) Setting {
+	return func(c Client) {
+		c.SetRedactionPolicy(policy)
+	}
+}
+
+// UseRequestHooks appends RequestHooks to be run just before every request.
+func UseRequestHooks(hooks ...RequestHook) Setting {
+	return func(c Client) {
+		c.AddRequestHooks(hooks...)
+	}
+}
+
+// UseResponseHooks appends ResponseHooks to be run just after every response
+// is received.
+func UseResponseHooks(hooks ...ResponseHook) Setting {
+	return func(c Client) {
+		c.AddResponseHooks(hooks...)
+	}
+}
+
+// UseRetryPolicy sets the policy used to retry failed requests. A nil policy
+// disables retries.
+func UseRetryPolicy(policy *RetryPolicy) Setting {
+	return func(c Client) {
+		c.SetRetryPolicy(policy)
+	}
+}
+
+// UseTimeout sets the time limit for requests, as http.Client's Timeout does.
+// The client continues to share its transport with the client it was derived
+// from.
+func UseTimeout(d time.Duration) Setting {
+	return func(c Client) {
+		c.SetTimeout(d)
+	}
+}
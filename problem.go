@@ -0,0 +1,110 @@
+package snorlax
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+)
+
+// problemContentType is the media type of RFC 7807 problem details.
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object, which many APIs return to
+// describe errors.
+type Problem struct {
+	// Type is a URI reference which identifies the problem type. It defaults
+	// to "about:blank" when the upstream omits it.
+	Type string `json:"type"`
+
+	// Title is a short, human-readable summary of the problem type.
+	Title string `json:"title,omitempty"`
+
+	// Status is the HTTP status code generated by the origin server.
+	Status int `json:"status,omitempty"`
+
+	// Detail is a human-readable explanation specific to this occurrence of
+	// the problem.
+	Detail string `json:"detail,omitempty"`
+
+	// Instance is a URI reference which identifies this occurrence of the
+	// problem.
+	Instance string `json:"instance,omitempty"`
+
+	// Extensions contains any additional members of the problem object.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// Error satisfies the error interface.
+func (p *Problem) Error() string {
+	msg := p.Title
+	if msg == "" {
+		msg = p.Type
+	}
+
+	if p.Detail != "" {
+		return fmt.Sprintf("%s: %s", msg, p.Detail)
+	}
+
+	return msg
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface, collecting any
+// non-standard members into Extensions.
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	type problem Problem
+	var standard problem
+	if err := json.Unmarshal(data, &standard); err != nil {
+		return err
+	}
+
+	var members map[string]interface{}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+
+	for _, key := range []string{"type", "title", "status", "detail",
+		"instance"} {
+		delete(members, key)
+	}
+
+	*p = Problem(standard)
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+
+	if len(members) > 0 {
+		p.Extensions = members
+	}
+
+	return nil
+}
+
+// isProblem returns whether the content type is that of RFC 7807 problem
+// details.
+func isProblem(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == problemContentType
+}
+
+// parseProblem decodes problem details from data if the content type
+// indicates they are present, returning nil otherwise.
+func parseProblem(contentType string, data []byte) *Problem {
+	if !isProblem(contentType) {
+		return nil
+	}
+
+	var p Problem
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil
+	}
+
+	return &p
+}
+
+// IsProblemType returns whether err carries RFC 7807 problem details with the
+// type URI.
+func IsProblemType(err error, typeURI string) bool {
+	var p *Problem
+	return errors.As(err, &p) && p.Type == typeURI
+}
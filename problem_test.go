@@ -0,0 +1,93 @@
+package snorlax_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/stretchr/testify/suite"
+)
+
+const outOfCredit = `{
+	"type": "https://example.com/probs/out-of-credit",
+	"title": "You do not have enough credit.",
+	"status": 403,
+	"detail": "Your current balance is 30, but that costs 50.",
+	"instance": "/account/12345/msgs/abc",
+	"balance": 30
+}`
+
+type ProblemTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+func (suite *ProblemTestSuite) SetupSuite() {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(outOfCredit))
+	}
+
+	suite.server = httptest.NewServer(http.HandlerFunc(h))
+}
+
+func (suite *ProblemTestSuite) TearDownSuite() {
+	suite.server.Close()
+}
+
+func (suite *ProblemTestSuite) TestResponse_Problem() {
+	client := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL)
+
+	res, err := client.Get(context.TODO(), "/msgs", nil)
+	suite.Require().NoError(err)
+	suite.Require().True(res.IsProblem())
+
+	p, err := res.Problem()
+	suite.Require().NoError(err)
+	suite.Require().Equal("https://example.com/probs/out-of-credit", p.Type)
+	suite.Require().Equal("You do not have enough credit.", p.Title)
+	suite.Require().Equal(http.StatusForbidden, p.Status)
+	suite.Require().Equal("/account/12345/msgs/abc", p.Instance)
+	suite.Require().Equal(map[string]interface{}{"balance": float64(30)},
+		p.Extensions)
+}
+
+func (suite *ProblemTestSuite) TestResponse_NotProblem() {
+	res := snorlax.Response{Response: http.Response{
+		Header: http.Header{"Content-Type": []string{"application/json"}},
+		Body:   ioutil.NopCloser(strings.NewReader(outOfCredit)),
+	}}
+
+	suite.Require().False(res.IsProblem())
+
+	p, err := res.Problem()
+	suite.Require().NoError(err)
+	suite.Require().Nil(p)
+}
+
+func (suite *ProblemTestSuite) TestHTTPError_Problem() {
+	client := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		SetHTTPErrors(true)
+
+	_, err := client.Get(context.TODO(), "/msgs", nil)
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Your current balance is 30")
+	suite.Require().True(snorlax.IsProblemType(err,
+		"https://example.com/probs/out-of-credit"))
+
+	var p *snorlax.Problem
+	suite.Require().True(errors.As(err, &p))
+	suite.Require().Equal(float64(30), p.Extensions["balance"])
+}
+
+func TestProblemTestSuite(t *testing.T) {
+	suite.Run(t, new(ProblemTestSuite))
+}
@@ -0,0 +1,130 @@
+package snorlax
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit describes the rate limiting state an upstream reported in a
+// response's headers.
+type RateLimit struct {
+	// Limit is the maximum number of requests allowed in the current window,
+	// or -1 if the upstream didn't report it.
+	Limit int
+
+	// Remaining is the number of requests left in the current window, or -1
+	// if the upstream didn't report it.
+	Remaining int
+
+	// Reset is the time at which the current window resets. It is the zero
+	// time if the upstream didn't report it.
+	Reset time.Time
+
+	// RetryAfter is the time the upstream asked clients to wait before
+	// sending another request, parsed from the Retry-After header.
+	RetryAfter time.Duration
+}
+
+// rateLimitHeaders lists the header name prefixes, in order of preference,
+// which upstreams commonly use to report rate limits.
+var rateLimitHeaders = []string{"X-RateLimit-", "RateLimit-", "X-Rate-Limit-"}
+
+// epochThreshold separates Reset header values given as a delay in seconds
+// from values given as a unix timestamp.
+const epochThreshold = 1000000000
+
+// parseRateLimit extracts the rate limit state from the response headers. It
+// returns nil if the response contains no rate limiting headers.
+func parseRateLimit(h http.Header, now time.Time) *RateLimit {
+	rl := RateLimit{Limit: -1, Remaining: -1}
+	found := false
+
+	if d, ok := parseRetryAfter(h.Get("Retry-After"), now); ok {
+		rl.RetryAfter = d
+		found = true
+	}
+
+	for _, prefix := range rateLimitHeaders {
+		if v, err := strconv.Atoi(h.Get(prefix + "Limit")); err == nil &&
+			rl.Limit < 0 {
+			rl.Limit = v
+			found = true
+		}
+
+		if v, err := strconv.Atoi(h.Get(prefix + "Remaining")); err == nil &&
+			rl.Remaining < 0 {
+			rl.Remaining = v
+			found = true
+		}
+
+		reset, err := strconv.ParseFloat(h.Get(prefix+"Reset"), 64)
+		if err != nil || !rl.Reset.IsZero() {
+			continue
+		}
+
+		if reset >= epochThreshold {
+			rl.Reset = time.Unix(0, int64(reset*float64(time.Second)))
+		} else {
+			rl.Reset = now.Add(time.Duration(reset * float64(time.Second)))
+		}
+		found = true
+	}
+
+	if !found {
+		return nil
+	}
+
+	return &rl
+}
+
+// parseRetryAfter parses a Retry-After header value, given either as a number
+// of seconds or as an HTTP-date, into the duration to wait from now.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+
+	return 0, true
+}
+
+// throttleDelay returns how long the upstream asked us to wait before retrying
+// a rate limited or unavailable response.
+func throttleDelay(res *http.Response, now time.Time) (time.Duration, bool) {
+	if res.StatusCode != http.StatusTooManyRequests &&
+		res.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	if d, ok := parseRetryAfter(res.Header.Get("Retry-After"), now); ok {
+		return d, true
+	}
+
+	rl := parseRateLimit(res.Header, now)
+	if rl != nil && rl.Remaining == 0 && !rl.Reset.IsZero() {
+		if d := rl.Reset.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
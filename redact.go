@@ -0,0 +1,173 @@
+package snorlax
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// redactedValue replaces sensitive values in logs when a RedactionPolicy
+// doesn't specify its own Replacement.
+const redactedValue = "[REDACTED]"
+
+// RedactionPolicy decides which header and query parameter values are masked
+// in the logs a Client writes. Passwords embedded in URLs are always masked.
+type RedactionPolicy struct {
+	// Headers lists the names of headers whose values are masked. Names are
+	// matched case-insensitively.
+	Headers []string
+
+	// QueryParams lists the names of query parameters whose values are
+	// masked. Names are matched case-insensitively.
+	QueryParams []string
+
+	// Patterns masks the values of any header or query parameter whose name
+	// matches one of the regular expressions.
+	Patterns []*regexp.Regexp
+
+	// Replacement is written in place of masked values. It defaults to
+	// "[REDACTED]".
+	Replacement string
+}
+
+// defaultRedaction is the policy used by clients which haven't set their own.
+var defaultRedaction = DefaultRedactionPolicy()
+
+// DefaultRedactionPolicy returns a RedactionPolicy which masks common
+// credential headers, such as Authorization, Cookie and X-Api-Key, and query
+// parameters, such as access_token and api_key, as well as any header or query
+// parameter whose name mentions a secret, token, password or API key.
+func DefaultRedactionPolicy() *RedactionPolicy {
+	return &RedactionPolicy{
+		Headers: []string{
+			"Authorization",
+			"Cookie",
+			"Proxy-Authorization",
+			"Set-Cookie",
+			"X-Api-Key",
+			"X-Auth-Token",
+		},
+		QueryParams: []string{
+			"access_token",
+			"api_key",
+			"apikey",
+			"key",
+			"password",
+			"signature",
+			"token",
+		},
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)(secret|token|passw(or)?d|api[-_]?key)`),
+		},
+	}
+}
+
+// clone returns a copy of the policy which doesn't share its lists of names
+// and patterns.
+func (p *RedactionPolicy) clone() *RedactionPolicy {
+	if p == nil {
+		return nil
+	}
+
+	policy := *p
+	policy.Headers = append([]string(nil), p.Headers...)
+	policy.QueryParams = append([]string(nil), p.QueryParams...)
+	policy.Patterns = append([]*regexp.Regexp(nil), p.Patterns...)
+
+	return &policy
+}
+
+// replacement returns the string masked values are replaced with.
+func (p *RedactionPolicy) replacement() string {
+	if p.Replacement == "" {
+		return redactedValue
+	}
+
+	return p.Replacement
+}
+
+// sensitive returns whether values named name are masked, given the list of
+// names configured for their kind.
+func (p *RedactionPolicy) sensitive(name string, names []string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+
+	for _, pattern := range p.Patterns {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Header returns the values of the header, masked if the header is sensitive.
+func (p *RedactionPolicy) Header(key string, values []string) []string {
+	if !p.sensitive(http.CanonicalHeaderKey(key), p.Headers) {
+		return values
+	}
+
+	masked := make([]string, len(values))
+	for i := range values {
+		masked[i] = p.replacement()
+	}
+
+	return masked
+}
+
+// URL returns the URL as a string, with the values of sensitive query
+// parameters and any password masked.
+func (p *RedactionPolicy) URL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	redacted := *u
+	if _, ok := u.User.Password(); ok {
+		redacted.User = url.UserPassword(u.User.Username(), p.replacement())
+	}
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for name, values := range query {
+			if p.sensitive(name, p.QueryParams) {
+				for i := range values {
+					values[i] = p.replacement()
+				}
+			}
+		}
+		redacted.RawQuery = query.Encode()
+	}
+
+	// The replacement is escaped when the URL is encoded, so it is restored
+	// to keep logs readable.
+	escaped := url.QueryEscape(p.replacement())
+	return strings.ReplaceAll(redacted.String(), escaped, p.replacement())
+}
+
+// rawURL masks the sensitive parts of a URL given as a string.
+func (p *RedactionPolicy) rawURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return p.replacement()
+	}
+
+	return p.URL(u)
+}
+
+// Error returns the error's message, with the URL of any *url.Error masked.
+func (p *RedactionPolicy) Error(err error) string {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err.Error()
+	}
+
+	return strings.ReplaceAll(err.Error(), fmt.Sprintf("%q", urlErr.URL),
+		fmt.Sprintf("%q", p.rawURL(urlErr.URL)))
+}
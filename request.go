@@ -1,6 +1,10 @@
 package snorlax
 
-import "net/http"
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
 
 type (
 	// RequestHook is a middleware function that can be applied to an HTTP
@@ -8,6 +12,23 @@ type (
 	RequestHook func(Client, *http.Request) error
 )
 
+// contextKey is the type of the keys used to attach per-request configuration
+// to a request's context.
+type contextKey int
+
+const (
+	retryPolicyKey contextKey = iota
+	middlewareKey
+	responseHooksKey
+	routeTemplateKey
+	signersKey
+)
+
+// withValue replaces the context of r with one carrying the key value pair.
+func withValue(r *http.Request, key contextKey, value interface{}) {
+	*r = *r.WithContext(context.WithValue(r.Context(), key, value))
+}
+
 // WithBasicAuth sets basic authentication on the request.
 func WithBasicAuth(username, password string) RequestHook {
 	return func(c Client, r *http.Request) error {
@@ -16,6 +37,67 @@ func WithBasicAuth(username, password string) RequestHook {
 	}
 }
 
+// APIKeyPlacement is where an API key is sent in a request.
+type APIKeyPlacement int
+
+const (
+	// APIKeyInHeader sends the API key as a request header.
+	APIKeyInHeader APIKeyPlacement = iota
+
+	// APIKeyInQuery sends the API key as a query parameter.
+	APIKeyInQuery
+)
+
+// WithAPIKey sets the API key on the request, as the header or query parameter
+// name.
+func WithAPIKey(name, key string, placement APIKeyPlacement) RequestHook {
+	return WithAPIKeyProvider(name, StaticToken(key), placement)
+}
+
+// WithAPIKeyProvider sets an API key provided by p on the request, as the
+// header or query parameter name.
+func WithAPIKeyProvider(name string, p TokenProvider,
+	placement APIKeyPlacement) RequestHook {
+	return func(c Client, r *http.Request) error {
+		key, err := p.Token(r.Context())
+		if err != nil {
+			return fmt.Errorf("failed to get api key: %w", err)
+		}
+
+		switch placement {
+		case APIKeyInHeader:
+			r.Header.Set(name, key)
+		case APIKeyInQuery:
+			query := r.URL.Query()
+			query.Set(name, key)
+			r.URL.RawQuery = query.Encode()
+		default:
+			return fmt.Errorf("invalid api key placement %d", placement)
+		}
+
+		return nil
+	}
+}
+
+// WithBearerToken sets bearer token authentication on the request.
+func WithBearerToken(token string) RequestHook {
+	return WithBearerTokenProvider(StaticToken(token))
+}
+
+// WithBearerTokenProvider sets bearer token authentication on the request,
+// using a token provided by p.
+func WithBearerTokenProvider(p TokenProvider) RequestHook {
+	return func(c Client, r *http.Request) error {
+		token, err := p.Token(r.Context())
+		if err != nil {
+			return fmt.Errorf("failed to get bearer token: %w", err)
+		}
+
+		r.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
 // WithHeader adds a the header key value pair to the request.
 func WithHeader(key, value string) RequestHook {
 	return func(c Client, r *http.Request) error {
@@ -23,3 +105,34 @@ func WithHeader(key, value string) RequestHook {
 		return nil
 	}
 }
+
+// WithRetryPolicy overrides the client's RetryPolicy for a single request. A
+// nil policy disables retries for the request.
+func WithRetryPolicy(policy *RetryPolicy) RequestHook {
+	return func(c Client, r *http.Request) error {
+		withValue(r, retryPolicyKey, policy)
+		return nil
+	}
+}
+
+// WithMiddleware wraps a single request with the Middleware. Request
+// Middleware runs inside any Middleware configured on the client.
+func WithMiddleware(middleware ...Middleware) RequestHook {
+	return func(c Client, r *http.Request) error {
+		existing, _ := r.Context().Value(middlewareKey).([]Middleware)
+		withValue(r, middlewareKey, append(append([]Middleware(nil),
+			existing...), middleware...))
+		return nil
+	}
+}
+
+// WithResponseHook adds ResponseHooks to be run after a single request
+// completes. They run after any ResponseHooks configured on the client.
+func WithResponseHook(hooks ...ResponseHook) RequestHook {
+	return func(c Client, r *http.Request) error {
+		existing, _ := r.Context().Value(responseHooksKey).([]ResponseHook)
+		withValue(r, responseHooksKey, append(append([]ResponseHook(nil),
+			existing...), hooks...))
+		return nil
+	}
+}
@@ -1,7 +1,9 @@
 package snorlax_test
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -44,6 +46,46 @@ func (suite *HooksTestSuite) TestWithHeader() {
 	suite.Require().Equal(headerValue, r.Header.Get(headerKey))
 }
 
+func (suite *HooksTestSuite) TestWithAPIKey_Header() {
+	r := httptest.NewRequest(http.MethodGet, "/test", nil)
+
+	err := snorlax.WithAPIKey("X-Api-Key", "s3cr3t",
+		snorlax.APIKeyInHeader)(suite.client, r)
+	suite.Require().NoError(err)
+	suite.Require().Equal("s3cr3t", r.Header.Get("X-Api-Key"))
+}
+
+func (suite *HooksTestSuite) TestWithAPIKey_Query() {
+	r := httptest.NewRequest(http.MethodGet, "/test?pokemon=snorlax", nil)
+
+	err := snorlax.WithAPIKey("api_key", "s3cr3t",
+		snorlax.APIKeyInQuery)(suite.client, r)
+	suite.Require().NoError(err)
+	suite.Require().Equal("s3cr3t", r.URL.Query().Get("api_key"))
+	suite.Require().Equal("snorlax", r.URL.Query().Get("pokemon"))
+	suite.Require().Empty(r.Header.Get("api_key"))
+}
+
+func (suite *HooksTestSuite) TestWithBearerToken() {
+	r := httptest.NewRequest(http.MethodGet, "/test", nil)
+
+	err := snorlax.WithBearerToken("s3cr3t")(suite.client, r)
+	suite.Require().NoError(err)
+	suite.Require().Equal("Bearer s3cr3t", r.Header.Get("Authorization"))
+}
+
+func (suite *HooksTestSuite) TestWithBearerTokenProvider_Error() {
+	r := httptest.NewRequest(http.MethodGet, "/test", nil)
+
+	err := snorlax.WithBearerTokenProvider(snorlax.TokenProviderFunc(
+		func(context.Context) (string, error) {
+			return "", errors.New("vault sealed")
+		}))(suite.client, r)
+	suite.Require().EqualError(err,
+		"failed to get bearer token: vault sealed")
+	suite.Require().Empty(r.Header.Get("Authorization"))
+}
+
 func TestHooksTestSuite(t *testing.T) {
 	suite.Run(t, new(HooksTestSuite))
 }
@@ -7,11 +7,24 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"time"
 )
 
+// ResponseHook is a function that is applied to a response after it's
+// received. Returning an error fails the request with that error.
+type ResponseHook func(Client, *Response) error
+
 // Response is a type alias for http.Response.
 type Response struct {
 	http.Response
+
+	// cacheStatus is set by the client's Cache, rather than carried in a
+	// header, so that upstreams can't spoof it.
+	cacheStatus CacheStatus
+
+	// redaction is the RedactionPolicy of the client which received the
+	// response, used to mask the URL in any HTTPError built from it.
+	redaction *RedactionPolicy
 }
 
 // IsSuccess returns whether the response code is within the 2XX range.
@@ -19,6 +32,18 @@ func (r *Response) IsSuccess() bool {
 	return r.StatusCode < http.StatusMultipleChoices
 }
 
+// CacheStatus returns how the client's Cache produced the response. It is
+// CacheNone if the response did not pass through a Cache.
+func (r *Response) CacheStatus() CacheStatus {
+	return r.cacheStatus
+}
+
+// IsProblem returns whether the response body is an RFC 7807
+// application/problem+json document.
+func (r *Response) IsProblem() bool {
+	return isProblem(r.Header.Get("Content-Type"))
+}
+
 // JSON reads and unmarshals the response body into out.
 func (r *Response) JSON(out interface{}) error {
 	defer r.Body.Close()
@@ -34,6 +59,28 @@ func (r *Response) JSON(out interface{}) error {
 	return nil
 }
 
+// Problem reads and decodes the RFC 7807 problem details in the response body.
+// It returns nil if the response isn't an application/problem+json document.
+func (r *Response) Problem() (*Problem, error) {
+	if !r.IsProblem() {
+		return nil, nil
+	}
+
+	var p Problem
+	if err := r.JSON(&p); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// RateLimit returns the rate limiting state reported in the response headers
+// through Retry-After and the common X-RateLimit-* and RateLimit-* headers. It
+// returns nil if the response contains none of them.
+func (r *Response) RateLimit() *RateLimit {
+	return parseRateLimit(r.Header, time.Now())
+}
+
 // RawBody returns an io.Reader containing the data returned in the response
 // body.
 func (r *Response) RawBody() (io.Reader, error) {
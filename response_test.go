@@ -6,7 +6,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/nickcorin/snorlax"
 	"github.com/stretchr/testify/suite"
@@ -38,7 +40,8 @@ func EchoHandler(w http.ResponseWriter, r *http.Request) {
 
 func (suite *ResponseTestSuite) SetupSuite() {
 	suite.server = httptest.NewServer(http.HandlerFunc(EchoHandler))
-	suite.client = snorlax.DefaultClient.SetBaseURL(suite.server.URL)
+	suite.client = snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL)
 }
 
 func (suite *ResponseTestSuite) TearDownSuite() {
@@ -46,11 +49,11 @@ func (suite *ResponseTestSuite) TearDownSuite() {
 }
 
 func (suite *ResponseTestSuite) TestIsSuccess() {
-	successResponse := snorlax.Response{http.Response{
+	successResponse := snorlax.Response{Response: http.Response{
 		StatusCode: http.StatusOK,
 	}}
 
-	failedResponse := snorlax.Response{http.Response{
+	failedResponse := snorlax.Response{Response: http.Response{
 		StatusCode: http.StatusInternalServerError,
 	}}
 
@@ -77,6 +80,38 @@ func (suite *ResponseTestSuite) TestJSON() {
 	suite.Require().Equal(143, pokemon.Number)
 }
 
+func (suite *ResponseTestSuite) TestRateLimit() {
+	reset := time.Now().Add(time.Minute).Truncate(time.Second)
+
+	res := snorlax.Response{Response: http.Response{Header: http.Header{
+		"X-Ratelimit-Limit":     []string{"100"},
+		"X-Ratelimit-Remaining": []string{"7"},
+		"X-Ratelimit-Reset":     []string{strconv.FormatInt(reset.Unix(), 10)},
+		"Retry-After":           []string{"5"},
+	}}}
+
+	rl := res.RateLimit()
+	suite.Require().NotNil(rl)
+	suite.Require().Equal(100, rl.Limit)
+	suite.Require().Equal(7, rl.Remaining)
+	suite.Require().True(reset.Equal(rl.Reset))
+	suite.Require().Equal(5*time.Second, rl.RetryAfter)
+
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	res = snorlax.Response{Response: http.Response{Header: http.Header{
+		"Retry-After": []string{date},
+	}}}
+
+	rl = res.RateLimit()
+	suite.Require().NotNil(rl)
+	suite.Require().Equal(-1, rl.Limit)
+	suite.Require().Equal(-1, rl.Remaining)
+	suite.Require().InDelta(time.Hour, rl.RetryAfter, float64(2*time.Second))
+
+	res = snorlax.Response{Response: http.Response{Header: http.Header{}}}
+	suite.Require().Nil(res.RateLimit())
+}
+
 func (suite *ResponseTestSuite) TestRawBody() {
 	type Pokemon struct {
 		Name   string `json:"name"`
@@ -0,0 +1,249 @@
+package snorlax
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures how a Client retries requests which fail with a
+// retryable error or status code.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is attempted,
+	// including the first attempt. Values less than 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the time waited before the second attempt.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the time waited between any two attempts.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor the backoff grows by after every attempt.
+	Multiplier float64
+
+	// Jitter is the fraction, between 0 and 1, by which each backoff is
+	// randomly adjusted to avoid many clients retrying in lockstep.
+	Jitter float64
+
+	// RetryableStatusCodes lists the response status codes which trigger a
+	// retry.
+	RetryableStatusCodes []int
+
+	// RespectRetryAfter makes the client wait for the time requested by 429
+	// and 503 responses through the Retry-After or X-RateLimit-Reset headers
+	// instead of the computed backoff. If the wait would outlast the
+	// request's context deadline, the response is returned instead.
+	RespectRetryAfter bool
+
+	// MaxRetryAfter caps the time the client is willing to wait when
+	// respecting Retry-After. Responses asking for a longer wait are returned
+	// without being retried. Zero means no cap.
+	MaxRetryAfter time.Duration
+
+	// ShouldRetry optionally overrides the default decision of whether a
+	// response or error is retryable.
+	ShouldRetry func(res *http.Response, err error) bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy which attempts requests up to three
+// times with exponential backoff, retrying network errors and 429, 502, 503 and
+// 504 responses. Rate limited responses are retried after the time requested by
+// the upstream, up to a minute.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:       3,
+		InitialBackoff:    100 * time.Millisecond,
+		MaxBackoff:        5 * time.Second,
+		Multiplier:        2,
+		Jitter:            0.2,
+		RespectRetryAfter: true,
+		MaxRetryAfter:     time.Minute,
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// clone returns a copy of the policy which doesn't share its status codes.
+func (p *RetryPolicy) clone() *RetryPolicy {
+	if p == nil {
+		return nil
+	}
+
+	policy := *p
+	policy.RetryableStatusCodes = append([]int(nil),
+		p.RetryableStatusCodes...)
+
+	return &policy
+}
+
+// enabled returns whether the policy allows more than a single attempt.
+func (p *RetryPolicy) enabled() bool {
+	return p != nil && p.MaxAttempts > 1
+}
+
+// retryable returns whether the outcome of an attempt should be retried.
+func (p *RetryPolicy) retryable(res *http.Response, err error) bool {
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(res, err)
+	}
+
+	if err != nil {
+		return isRetryableError(err)
+	}
+
+	for _, code := range p.RetryableStatusCodes {
+		if res.StatusCode == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff returns the time to wait after the given attempt has failed.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	return time.Duration(d)
+}
+
+// wait returns how long to wait before retrying an attempt which returned res,
+// and whether the attempt should be retried at all given the upstream's
+// requested delay and the context deadline.
+func (p *RetryPolicy) wait(ctx context.Context, attempt int,
+	res *http.Response) (time.Duration, bool) {
+	backoff := p.backoff(attempt)
+	if res == nil || !p.RespectRetryAfter {
+		return backoff, true
+	}
+
+	now := time.Now()
+	d, ok := throttleDelay(res, now)
+	if !ok {
+		return backoff, true
+	}
+
+	if p.MaxRetryAfter > 0 && d > p.MaxRetryAfter {
+		return 0, false
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && now.Add(d).After(deadline) {
+		return 0, false
+	}
+
+	return d, true
+}
+
+// isRetryableError returns whether err is a transient network error which is
+// likely to succeed if the request is sent again.
+func isRetryableError(err error) bool {
+	if errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// sleep waits for d, returning early with the context's error if it is done
+// first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// bufferBody reads the request body into memory, if it isn't already
+// replayable, so that it can be sent again on subsequent attempts.
+func bufferBody(req *http.Request) error {
+	if rewindable(req) {
+		return nil
+	}
+
+	defer req.Body.Close()
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	req.ContentLength = int64(len(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.Body, _ = req.GetBody()
+
+	return nil
+}
+
+// rewindable returns whether req can be sent again, because it has no body or
+// its body can be replayed.
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// discard drains and closes the response body, so that its connection can be
+// reused.
+func discard(res *http.Response) {
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+}
+
+// rewind returns a copy of req with a fresh body, ready to be sent again.
+func rewind(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody == nil {
+		return r, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind request body: %w", err)
+	}
+	r.Body = body
+
+	return r, nil
+}
@@ -0,0 +1,157 @@
+package snorlax_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/stretchr/testify/suite"
+)
+
+type RetryTestSuite struct {
+	suite.Suite
+	attempts int32
+	bodies   chan string
+	client   snorlax.Client
+	server   *httptest.Server
+}
+
+func (suite *RetryTestSuite) SetupTest() {
+	atomic.StoreInt32(&suite.attempts, 0)
+	suite.bodies = make(chan string, 10)
+
+	// The handler fails the first two attempts of every test.
+	h := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		suite.bodies <- string(body)
+
+		if atomic.AddInt32(&suite.attempts, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+
+	suite.server = httptest.NewServer(http.HandlerFunc(h))
+
+	policy := snorlax.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+
+	suite.client = snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		SetRetryPolicy(policy)
+}
+
+func (suite *RetryTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *RetryTestSuite) TestRetry_ResendsBody() {
+	res, err := suite.client.Post(context.TODO(), "/retry", nil,
+		ioutil.NopCloser(bytes.NewBufferString("snorlax")))
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, res.StatusCode)
+	suite.Require().EqualValues(3, atomic.LoadInt32(&suite.attempts))
+
+	for i := 0; i < 3; i++ {
+		suite.Require().Equal("snorlax", <-suite.bodies)
+	}
+}
+
+func (suite *RetryTestSuite) TestRetry_AttemptsExhausted() {
+	policy := snorlax.DefaultRetryPolicy()
+	policy.MaxAttempts = 2
+	policy.InitialBackoff = time.Millisecond
+
+	res, err := suite.client.Get(context.TODO(), "/retry", nil,
+		snorlax.WithRetryPolicy(policy))
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusServiceUnavailable, res.StatusCode)
+	suite.Require().EqualValues(2, atomic.LoadInt32(&suite.attempts))
+}
+
+func (suite *RetryTestSuite) TestRetry_DisabledPerRequest() {
+	res, err := suite.client.Get(context.TODO(), "/retry", nil,
+		snorlax.WithRetryPolicy(nil))
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusServiceUnavailable, res.StatusCode)
+	suite.Require().EqualValues(1, atomic.LoadInt32(&suite.attempts))
+}
+
+func (suite *RetryTestSuite) TestRetry_ContextCancelled() {
+	policy := snorlax.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+
+	_, err := suite.client.Get(ctx, "/retry", nil,
+		snorlax.WithRetryPolicy(policy))
+	suite.Require().True(errors.Is(err, context.DeadlineExceeded))
+	suite.Require().EqualValues(1, atomic.LoadInt32(&suite.attempts))
+}
+
+func (suite *RetryTestSuite) TestRetry_RetryAfter() {
+	var attempts int32
+	h := func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(h))
+	defer server.Close()
+
+	start := time.Now()
+	client := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(server.URL).
+		SetRetryPolicy(snorlax.DefaultRetryPolicy())
+
+	res, err := client.Get(context.TODO(), "/retry", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, res.StatusCode)
+	suite.Require().EqualValues(2, atomic.LoadInt32(&attempts))
+	suite.Require().True(time.Since(start) >= time.Second)
+}
+
+func (suite *RetryTestSuite) TestRetry_RetryAfterExceedsDeadline() {
+	var attempts int32
+	h := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(h))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	client := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(server.URL).
+		SetRetryPolicy(snorlax.DefaultRetryPolicy())
+
+	res, err := client.Get(ctx, "/retry", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusTooManyRequests, res.StatusCode)
+	suite.Require().EqualValues(1, atomic.LoadInt32(&attempts))
+	suite.Require().Equal(30*time.Second, res.RateLimit().RetryAfter)
+}
+
+func TestRetryTestSuite(t *testing.T) {
+	suite.Run(t, new(RetryTestSuite))
+}
@@ -0,0 +1,85 @@
+package snorlax
+
+import (
+	"net/http"
+	"strings"
+)
+
+// unmatchedRoute is the metrics path label used for requests whose path isn't
+// described by a route template.
+const unmatchedRoute = "other"
+
+// PathNormalizer maps a request to the route template used to label its
+// metrics, such as "/users/{id}/orders". It returns an empty string if it
+// doesn't recognise the request.
+type PathNormalizer func(r *http.Request) string
+
+// RouteTemplates returns a PathNormalizer which matches the request path
+// against the templates, in order. Segments wrapped in braces, such as "{id}",
+// match any single path segment.
+func RouteTemplates(templates ...string) PathNormalizer {
+	split := make([][]string, len(templates))
+	for i, template := range templates {
+		split[i] = strings.Split(strings.Trim(template, "/"), "/")
+	}
+
+	return func(r *http.Request) string {
+		segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		for i, template := range split {
+			if matchRoute(template, segments) {
+				return templates[i]
+			}
+		}
+
+		return ""
+	}
+}
+
+// matchRoute returns whether the path segments match the template segments.
+func matchRoute(template, segments []string) bool {
+	if len(template) != len(segments) {
+		return false
+	}
+
+	for i, t := range template {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+
+		if t != segments[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WithRouteTemplate sets the route template, such as "/users/{id}", used to
+// label the request's metrics instead of its path.
+func WithRouteTemplate(template string) RequestHook {
+	return func(c Client, r *http.Request) error {
+		withValue(r, routeTemplateKey, template)
+		return nil
+	}
+}
+
+// routeLabel returns the path label for the request's metrics. Paths which
+// aren't described by a route template are collapsed into a single label, so
+// that requests to dynamic paths can't overload Prometheus.
+func routeLabel(r *http.Request, normalizer PathNormalizer) string {
+	if template, ok := r.Context().Value(routeTemplateKey).(string); ok &&
+		template != "" {
+		return template
+	}
+
+	if normalizer != nil {
+		if template := normalizer(r); template != "" {
+			return template
+		}
+	}
+
+	return unmatchedRoute
+}
@@ -0,0 +1,97 @@
+package snorlax_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/suite"
+)
+
+type RoutesTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+}
+
+func (suite *RoutesTestSuite) SetupSuite() {
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}
+
+	suite.server = httptest.NewServer(http.HandlerFunc(h))
+}
+
+func (suite *RoutesTestSuite) TearDownSuite() {
+	suite.server.Close()
+}
+
+// pathLabels returns the path labels of the latency histogram series with the
+// status code.
+func (suite *RoutesTestSuite) pathLabels(code string) []string {
+	families, err := prometheus.DefaultGatherer.Gather()
+	suite.Require().NoError(err)
+
+	var paths []string
+	for _, family := range families {
+		if family.GetName() != "snorlax_requests_latency" {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+
+			if labels["code"] == code {
+				paths = append(paths, labels["path"])
+			}
+		}
+	}
+
+	return paths
+}
+
+func (suite *RoutesTestSuite) TestRouteTemplates() {
+	normalizer := snorlax.RouteTemplates("/users/{id}", "/users/{id}/orders")
+
+	tests := map[string]string{
+		"/users/143":        "/users/{id}",
+		"/users/143/orders": "/users/{id}/orders",
+		"/users/":           "",
+		"/users/143/items":  "",
+	}
+
+	for path, template := range tests {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		suite.Require().Equal(template, normalizer(r), path)
+	}
+}
+
+func (suite *RoutesTestSuite) TestMetricsLabels() {
+	opts := snorlax.Defaults()
+	opts.BaseURL = suite.server.URL
+	opts.PathNormalizer = snorlax.RouteTemplates("/users/{id}")
+	opts.WithMetrics = true
+
+	client := snorlax.NewClient(opts)
+
+	for _, path := range []string{"/users/1", "/users/2", "/pokemon/143"} {
+		_, err := client.Get(context.TODO(), path, nil)
+		suite.Require().NoError(err)
+	}
+
+	_, err := client.Get(context.TODO(), "/orders/1", nil,
+		snorlax.WithRouteTemplate("/orders/{id}"))
+	suite.Require().NoError(err)
+
+	suite.Require().ElementsMatch([]string{"/users/{id}", "/orders/{id}",
+		"other"}, suite.pathLabels("202"))
+}
+
+func TestRoutesTestSuite(t *testing.T) {
+	suite.Run(t, new(RoutesTestSuite))
+}
@@ -0,0 +1,78 @@
+package snorlax
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// Signer signs a request, usually by adding headers computed from its method,
+// URL, headers and body.
+//
+// Signers run just before each attempt to send a request, after all of the
+// RequestHooks and Middleware, so that the signature covers the request
+// exactly as it is sent, and is fresh when a request is retried.
+type Signer interface {
+	Sign(req *http.Request) error
+}
+
+// SignerFunc is an adapter which allows ordinary functions to be used as
+// Signers.
+type SignerFunc func(req *http.Request) error
+
+// Sign calls f(req).
+func (f SignerFunc) Sign(req *http.Request) error {
+	return f(req)
+}
+
+// WithSigner signs the request using the Signers. Signers run in the order
+// they are added, so add one with AddRequestHook to sign all of a client's
+// requests.
+func WithSigner(signers ...Signer) RequestHook {
+	return func(c Client, r *http.Request) error {
+		existing, _ := r.Context().Value(signersKey).([]Signer)
+		withValue(r, signersKey, append(append([]Signer(nil), existing...),
+			signers...))
+		return nil
+	}
+}
+
+// sign is the innermost Middleware, which signs the request using the Signers
+// attached to it by WithSigner.
+func sign(next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		signers, _ := req.Context().Value(signersKey).([]Signer)
+		if len(signers) == 0 {
+			return next.Do(req)
+		}
+
+		signed := req.Clone(req.Context())
+		for _, s := range signers {
+			if err := s.Sign(signed); err != nil {
+				return nil, fmt.Errorf("failed to sign request: %w", err)
+			}
+		}
+
+		return next.Do(signed)
+	})
+}
+
+// peekBody returns the request's body without consuming it. If the body can't
+// be read again using GetBody, it is read and replaced by one which can.
+func peekBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if err := bufferBody(req); err != nil {
+		return nil, err
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get request body: %w", err)
+	}
+	defer body.Close()
+
+	return ioutil.ReadAll(body)
+}
@@ -0,0 +1,530 @@
+package snorlax
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sigV4Algorithm       = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat      = "20060102T150405Z"
+	sigV4DateFormat      = "20060102"
+	sigV4UnsignedPayload = "UNSIGNED-PAYLOAD"
+	sigV4StreamPayload   = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	sigV4ChunkAlgorithm  = "AWS4-HMAC-SHA256-PAYLOAD"
+
+	// sigV4EmptyHash is the hex encoded SHA-256 hash of an empty payload.
+	sigV4EmptyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// sigV4IgnoredHeaders are not signed, because they are commonly changed after
+// a request is signed.
+var sigV4IgnoredHeaders = map[string]bool{
+	"authorization":     true,
+	"connection":        true,
+	"expect":            true,
+	"transfer-encoding": true,
+	"user-agent":        true,
+	"x-amzn-trace-id":   true,
+}
+
+// AWSCredentials are the credentials used to sign requests using SigV4.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// SessionToken is set when using temporary security credentials.
+	SessionToken string
+
+	// Expires is when temporary credentials expire. A zero Expires means the
+	// credentials do not expire.
+	Expires time.Time
+}
+
+// AWSCredentialsProvider provides the AWSCredentials used to sign each
+// request.
+type AWSCredentialsProvider interface {
+	Credentials(ctx context.Context) (AWSCredentials, error)
+}
+
+// AWSCredentialsProviderFunc is an adapter which allows ordinary functions to
+// be used as AWSCredentialsProviders.
+type AWSCredentialsProviderFunc func(ctx context.Context) (AWSCredentials,
+	error)
+
+// Credentials calls f(ctx).
+func (f AWSCredentialsProviderFunc) Credentials(ctx context.Context) (
+	AWSCredentials, error) {
+	return f(ctx)
+}
+
+// StaticAWSCredentials returns an AWSCredentialsProvider which always provides
+// the same credentials. The session token is optional.
+func StaticAWSCredentials(accessKeyID, secretAccessKey,
+	sessionToken string) AWSCredentialsProvider {
+	creds := AWSCredentials{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		SessionToken:    sessionToken,
+	}
+
+	return AWSCredentialsProviderFunc(func(context.Context) (AWSCredentials,
+		error) {
+		return creds, nil
+	})
+}
+
+// RefreshingAWSCredentials returns an AWSCredentialsProvider which caches the
+// credentials provided by p, such as temporary credentials from STS or an
+// instance metadata service, until window before they expire. Errors are not
+// cached.
+func RefreshingAWSCredentials(p AWSCredentialsProvider,
+	window time.Duration) AWSCredentialsProvider {
+	return &refreshingAWSCredentials{provider: p, window: window}
+}
+
+type refreshingAWSCredentials struct {
+	provider AWSCredentialsProvider
+	window   time.Duration
+
+	mu    sync.Mutex
+	creds *AWSCredentials
+}
+
+// Credentials satisfies the AWSCredentialsProvider interface. Concurrent
+// callers wait for a single call to the underlying provider.
+func (r *refreshingAWSCredentials) Credentials(ctx context.Context) (
+	AWSCredentials, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.creds != nil && (r.creds.Expires.IsZero() ||
+		time.Now().Add(r.window).Before(r.creds.Expires)) {
+		return *r.creds, nil
+	}
+
+	creds, err := r.provider.Credentials(ctx)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+
+	r.creds = &creds
+	return creds, nil
+}
+
+// SigV4Payload is how a SigV4 signature covers the request body.
+type SigV4Payload int
+
+const (
+	// SignedPayload includes the hash of the body in the signature. The body
+	// is read into memory to hash it, unless it can be read again using the
+	// request's GetBody.
+	SignedPayload SigV4Payload = iota
+
+	// UnsignedPayload excludes the body from the signature. It is supported by
+	// S3 over HTTPS.
+	UnsignedPayload
+
+	// StreamingPayload sends the body in signed chunks, using the aws-chunked
+	// content encoding supported by S3. The body is not read into memory
+	// unless its length is unknown.
+	StreamingPayload
+)
+
+// SigV4 signs requests using AWS Signature Version 4. Add it to a client using
+// WithSigner.
+type SigV4 struct {
+	// Service is the signing name of the service, such as s3 or execute-api.
+	Service string
+
+	// Region is the region of the service, such as us-east-1.
+	Region string
+
+	// Credentials provides the credentials used to sign each request.
+	Credentials AWSCredentialsProvider
+
+	// Payload is how the signature covers the request body.
+	Payload SigV4Payload
+
+	// ChunkSize is the size of the chunks a StreamingPayload is sent in. If
+	// zero, 64KiB chunks are sent.
+	ChunkSize int
+
+	// DisableURIPathEscaping escapes the request path once when signing it,
+	// rather than escaping the escaped path a second time. This is how S3
+	// signs requests, so it is implied when the Service is s3.
+	DisableURIPathEscaping bool
+
+	// Now returns the time requests are signed at. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+// NewSigV4 returns a SigV4 which signs requests for the service in the region.
+func NewSigV4(service, region string, creds AWSCredentialsProvider) *SigV4 {
+	return &SigV4{
+		Service:     service,
+		Region:      region,
+		Credentials: creds,
+	}
+}
+
+// Sign satisfies the Signer interface.
+func (s *SigV4) Sign(req *http.Request) error {
+	if s.Credentials == nil {
+		return errors.New("sigv4: no credentials provider")
+	}
+
+	creds, err := s.Credentials.Credentials(req.Context())
+	if err != nil {
+		return fmt.Errorf("sigv4: failed to get credentials: %w", err)
+	}
+
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	t := now().UTC()
+	amzDate := t.Format(sigV4TimeFormat)
+	scope := strings.Join([]string{t.Format(sigV4DateFormat), s.Region,
+		s.Service, "aws4_request"}, "/")
+
+	req.Header.Del("Authorization")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	signContentLength := false
+
+	var payloadHash string
+	switch {
+	case s.Payload == UnsignedPayload:
+		payloadHash = sigV4UnsignedPayload
+	case s.Payload == StreamingPayload && hasBody:
+		if err = s.chunk(req); err != nil {
+			return err
+		}
+		payloadHash = sigV4StreamPayload
+		signContentLength = true
+	default:
+		body, err := peekBody(req)
+		if err != nil {
+			return fmt.Errorf("sigv4: %w", err)
+		}
+		payloadHash = hexSHA256(body)
+	}
+
+	if s.Service == "s3" || payloadHash == sigV4UnsignedPayload ||
+		payloadHash == sigV4StreamPayload {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	headers, signedHeaders := sigV4Headers(req, signContentLength)
+
+	// Every service but S3 signs the normalized path.
+	uri := req.URL.Path
+	if s.Service != "s3" {
+		uri = sigV4Normalize(uri)
+	}
+
+	uri = sigV4Escape(uri, false)
+	if !s.DisableURIPathEscaping && s.Service != "s3" {
+		uri = sigV4Escape(uri, false)
+	}
+	if uri == "" {
+		uri = "/"
+	}
+
+	canonical := strings.Join([]string{
+		req.Method,
+		uri,
+		sigV4Query(req.URL),
+		headers,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonical)),
+	}, "\n")
+
+	key := sigV4Key(creds.SecretAccessKey, t.Format(sigV4DateFormat),
+		s.Region, s.Service)
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, creds.AccessKeyID, scope, signedHeaders, signature))
+
+	if r, ok := req.Body.(*sigV4ChunkReader); ok {
+		r.start(key, amzDate, scope, signature)
+	}
+
+	return nil
+}
+
+// chunk replaces the request's body with one which is sent in signed chunks,
+// and sets the headers describing it. The chunks are signed once the request's
+// signature is known.
+func (s *SigV4) chunk(req *http.Request) error {
+	if req.ContentLength <= 0 {
+		body, err := peekBody(req)
+		if err != nil {
+			return fmt.Errorf("sigv4: %w", err)
+		}
+		req.ContentLength = int64(len(body))
+	}
+
+	size := s.ChunkSize
+	if size <= 0 {
+		size = 64 << 10
+	}
+
+	decoded := req.ContentLength
+	encoded := (decoded / int64(size)) * sigV4ChunkLength(int64(size))
+	if rem := decoded % int64(size); rem > 0 {
+		encoded += sigV4ChunkLength(rem)
+	}
+	encoded += sigV4ChunkLength(0)
+
+	if enc := req.Header.Get("Content-Encoding"); enc != "" {
+		req.Header.Set("Content-Encoding", "aws-chunked,"+enc)
+	} else {
+		req.Header.Set("Content-Encoding", "aws-chunked")
+	}
+	req.Header.Set("X-Amz-Decoded-Content-Length",
+		strconv.FormatInt(decoded, 10))
+	req.ContentLength = encoded
+
+	r := &sigV4ChunkReader{body: req.Body, size: size}
+	req.Body = r
+
+	if getBody := req.GetBody; getBody != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+
+			return r.reset(body), nil
+		}
+	}
+
+	return nil
+}
+
+// sigV4Normalize removes the dot segments and empty segments from the path,
+// keeping any trailing slash.
+func sigV4Normalize(p string) string {
+	clean := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && clean != "/" {
+		clean += "/"
+	}
+
+	return clean
+}
+
+// sigV4ChunkLength returns the encoded length of a chunk of n bytes.
+func sigV4ChunkLength(n int64) int64 {
+	return int64(len(strconv.FormatInt(n, 16))+len(";chunk-signature=")+
+		64+2) + n + 2
+}
+
+// sigV4ChunkReader encodes a body as signed aws-chunked chunks. Each chunk's
+// signature chains from the previous one, starting with the request's
+// signature.
+type sigV4ChunkReader struct {
+	body  io.ReadCloser
+	size  int
+	key   []byte
+	date  string
+	scope string
+	seed  string
+	prev  string
+	buf   *bytes.Buffer
+	done  bool
+}
+
+// start sets the signing parameters once the request has been signed.
+func (r *sigV4ChunkReader) start(key []byte, date, scope, seed string) {
+	r.key, r.date, r.scope, r.seed, r.prev = key, date, scope, seed, seed
+}
+
+// reset returns a reader which encodes body from the first chunk, using the
+// same signing parameters as r.
+func (r *sigV4ChunkReader) reset(body io.ReadCloser) *sigV4ChunkReader {
+	return &sigV4ChunkReader{
+		body:  body,
+		size:  r.size,
+		key:   r.key,
+		date:  r.date,
+		scope: r.scope,
+		seed:  r.seed,
+		prev:  r.seed,
+	}
+}
+
+// Read satisfies the io.Reader interface.
+func (r *sigV4ChunkReader) Read(p []byte) (int, error) {
+	if r.buf == nil {
+		r.buf = new(bytes.Buffer)
+	}
+
+	for r.buf.Len() == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		data := make([]byte, r.size)
+		n, err := io.ReadFull(r.body, data)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+
+		r.writeChunk(data[:n])
+		if n == 0 {
+			r.done = true
+		} else if n < r.size {
+			// The body is exhausted, so finish with an empty chunk.
+			r.writeChunk(nil)
+			r.done = true
+		}
+	}
+
+	return r.buf.Read(p)
+}
+
+// writeChunk signs and encodes the chunk.
+func (r *sigV4ChunkReader) writeChunk(data []byte) {
+	stringToSign := strings.Join([]string{
+		sigV4ChunkAlgorithm,
+		r.date,
+		r.scope,
+		r.prev,
+		sigV4EmptyHash,
+		hexSHA256(data),
+	}, "\n")
+	r.prev = hex.EncodeToString(hmacSHA256(r.key, stringToSign))
+
+	fmt.Fprintf(r.buf, "%x;chunk-signature=%s\r\n", len(data), r.prev)
+	r.buf.Write(data)
+	r.buf.WriteString("\r\n")
+}
+
+// Close satisfies the io.Closer interface.
+func (r *sigV4ChunkReader) Close() error {
+	return r.body.Close()
+}
+
+// sigV4Headers returns the canonical headers of the request, and the list of
+// signed headers.
+func sigV4Headers(req *http.Request, contentLength bool) (string, string) {
+	values := make(map[string][]string)
+	for k, v := range req.Header {
+		k = strings.ToLower(k)
+		if sigV4IgnoredHeaders[k] {
+			continue
+		}
+		values[k] = append(values[k], v...)
+	}
+
+	values["host"] = []string{requestHost(req)}
+
+	if contentLength {
+		values["content-length"] = []string{
+			strconv.FormatInt(req.ContentLength, 10)}
+	}
+
+	names := make([]string, 0, len(values))
+	for k := range values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, k := range names {
+		trimmed := make([]string, len(values[k]))
+		for i, v := range values[k] {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+
+		b.WriteString(k + ":" + strings.Join(trimmed, ",") + "\n")
+	}
+
+	return b.String(), strings.Join(names, ";")
+}
+
+// sigV4Query returns the canonical query string of the URL.
+func sigV4Query(u *url.URL) string {
+	query := u.Query()
+
+	params := make([]string, 0, len(query))
+	for k, values := range query {
+		for _, v := range values {
+			params = append(params, sigV4Escape(k, true)+"="+
+				sigV4Escape(v, true))
+		}
+	}
+	sort.Strings(params)
+
+	return strings.Join(params, "&")
+}
+
+// sigV4Escape percent encodes every byte of s except the unreserved
+// characters, and except / unless escapeSlash is set.
+func sigV4Escape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z',
+			'0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+// sigV4Key derives the signing key for the date, region and service.
+func sigV4Key(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data using key.
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// hexSHA256 returns the hex encoded SHA-256 hash of data.
+func hexSHA256(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,168 @@
+// Package snorlaxotel provides OpenTelemetry tracing for Snorlax clients. It
+// starts a client span for every request a client sends, and propagates the
+// trace context to the upstream through the request headers.
+package snorlaxotel
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/nickcorin/snorlax"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the instrumentation library to OpenTelemetry.
+const tracerName = "github.com/nickcorin/snorlax/snorlaxotel"
+
+// Option configures the tracing Middleware.
+type Option func(*config)
+
+type config struct {
+	provider    trace.TracerProvider
+	propagators propagation.TextMapPropagator
+	redaction   *snorlax.RedactionPolicy
+	b3          bool
+}
+
+// WithTracerProvider sets the TracerProvider used to start spans. It defaults
+// to the global TracerProvider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = provider
+	}
+}
+
+// WithPropagators sets the propagators used to inject the trace context into
+// requests. It defaults to the global TextMapPropagator, falling back to W3C
+// Trace Context and Baggage if none is set.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagators = propagators
+	}
+}
+
+// WithRedactionPolicy sets the policy deciding which query parameters are
+// masked in the URLs recorded on spans. Pass the client's own policy to mask
+// the same values as its logs. It defaults to snorlax.DefaultRedactionPolicy.
+func WithRedactionPolicy(policy *snorlax.RedactionPolicy) Option {
+	return func(c *config) {
+		c.redaction = policy
+	}
+}
+
+// WithB3 additionally injects the trace context using the B3 multiple header
+// format, for upstreams which don't support W3C Trace Context.
+func WithB3() Option {
+	return func(c *config) {
+		c.b3 = true
+	}
+}
+
+// Middleware returns snorlax.Middleware which traces requests. Each request
+// sent is recorded as a client span, which continues any span in the request's
+// context. Retried requests produce one span per attempt.
+func Middleware(opts ...Option) snorlax.Middleware {
+	c := config{
+		provider:    otel.GetTracerProvider(),
+		propagators: otel.GetTextMapPropagator(),
+	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	if c.redaction == nil {
+		c.redaction = snorlax.DefaultRedactionPolicy()
+	}
+
+	// The global propagator is a no-op until one is configured, which would
+	// silently break propagation for callers who only set up tracing.
+	if len(c.propagators.Fields()) == 0 {
+		c.propagators = propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{}, propagation.Baggage{})
+	}
+
+	if c.b3 {
+		c.propagators = propagation.NewCompositeTextMapPropagator(
+			c.propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+	}
+
+	tracer := c.provider.Tracer(tracerName)
+
+	return func(next snorlax.Doer) snorlax.Doer {
+		return snorlax.DoerFunc(func(r *http.Request) (*http.Response,
+			error) {
+			ctx, span := tracer.Start(r.Context(), r.Method,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(requestAttributes(r, c.redaction)...))
+			defer span.End()
+
+			// The request is cloned so that the injected headers don't
+			// leak into headers shared with other requests.
+			r = r.Clone(ctx)
+			c.propagators.Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+			res, err := next.Do(r)
+			if err != nil {
+				span.SetAttributes(semconv.ErrorTypeKey.String(
+					fmt.Sprintf("%T", err)))
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				return res, err
+			}
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(res.StatusCode))
+			if res.StatusCode >= http.StatusBadRequest {
+				span.SetAttributes(semconv.ErrorTypeKey.String(
+					strconv.Itoa(res.StatusCode)))
+				span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+			}
+
+			return res, nil
+		})
+	}
+}
+
+// requestAttributes returns the HTTP client semantic convention attributes
+// describing the request.
+func requestAttributes(r *http.Request,
+	redaction *snorlax.RedactionPolicy) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.URLFull(redactedURL(r, redaction)),
+	}
+
+	host, port := r.URL.Hostname(), r.URL.Port()
+	if port == "" {
+		port = "80"
+		if r.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	if host != "" {
+		attrs = append(attrs, semconv.ServerAddress(host))
+	}
+
+	if p, err := strconv.Atoi(port); err == nil {
+		attrs = append(attrs, semconv.ServerPort(p))
+	}
+
+	return attrs
+}
+
+// redactedURL returns the request URL without any user credentials, which
+// must not be recorded on spans, and with sensitive query parameters masked.
+func redactedURL(r *http.Request, redaction *snorlax.RedactionPolicy) string {
+	u := *r.URL
+	u.User = nil
+
+	return redaction.URL(&u)
+}
@@ -0,0 +1,169 @@
+package snorlaxotel_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/nickcorin/snorlax/snorlaxotel"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type TracingTestSuite struct {
+	suite.Suite
+	exporter *tracetest.InMemoryExporter
+	provider *sdktrace.TracerProvider
+	headers  chan http.Header
+	server   *httptest.Server
+}
+
+func (suite *TracingTestSuite) SetupTest() {
+	suite.exporter = tracetest.NewInMemoryExporter()
+	suite.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(suite.exporter))
+	suite.headers = make(chan http.Header, 1)
+
+	h := func(w http.ResponseWriter, r *http.Request) {
+		suite.headers <- r.Header
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+
+	suite.server = httptest.NewServer(http.HandlerFunc(h))
+}
+
+func (suite *TracingTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+// client returns a client traced with the suite's TracerProvider.
+func (suite *TracingTestSuite) client(opts ...snorlaxotel.Option) snorlax.Client {
+	opts = append(opts, snorlaxotel.WithTracerProvider(suite.provider))
+
+	return snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(suite.server.URL).
+		AddMiddleware(snorlaxotel.Middleware(opts...))
+}
+
+// attributes returns the span's attributes as a map.
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+
+	return attrs
+}
+
+func (suite *TracingTestSuite) TestMiddleware_Span() {
+	ctx, parent := suite.provider.Tracer("test").Start(context.Background(),
+		"parent")
+
+	_, err := suite.client().Get(ctx, "/pokemon", nil)
+	suite.Require().NoError(err)
+	parent.End()
+
+	spans := suite.exporter.GetSpans()
+	suite.Require().Len(spans, 2)
+
+	span := spans[0]
+	suite.Require().Equal("GET", span.Name)
+	suite.Require().Equal(trace.SpanKindClient, span.SpanKind)
+	suite.Require().Equal(parent.SpanContext().SpanID(), span.Parent.SpanID())
+	suite.Require().Equal(codes.Unset, span.Status.Code)
+
+	attrs := attributes(span)
+	suite.Require().Equal("GET", attrs["http.request.method"].AsString())
+	suite.Require().Equal(suite.server.URL+"/pokemon",
+		attrs["url.full"].AsString())
+	suite.Require().EqualValues(200,
+		attrs["http.response.status_code"].AsInt64())
+
+	headers := <-suite.headers
+	suite.Require().Contains(headers.Get("Traceparent"),
+		span.SpanContext.TraceID().String())
+	suite.Require().Contains(headers.Get("Traceparent"),
+		span.SpanContext.SpanID().String())
+}
+
+func (suite *TracingTestSuite) TestMiddleware_RedactsURL() {
+	_, err := suite.client().Get(context.Background(), "/pokemon",
+		url.Values{"name": {"snorlax"}},
+		snorlax.WithAPIKey("api_key", "s3cr3t", snorlax.APIKeyInQuery))
+	suite.Require().NoError(err)
+	<-suite.headers
+
+	spans := suite.exporter.GetSpans()
+	suite.Require().Len(spans, 1)
+	suite.Require().Equal(suite.server.URL+
+		"/pokemon?api_key=[REDACTED]&name=snorlax",
+		attributes(spans[0])["url.full"].AsString())
+
+	// The client's own policy can be used instead.
+	suite.exporter.Reset()
+	policy := &snorlax.RedactionPolicy{QueryParams: []string{"name"}}
+	_, err = suite.client(snorlaxotel.WithRedactionPolicy(policy)).Get(
+		context.Background(), "/pokemon", url.Values{"name": {"snorlax"}})
+	suite.Require().NoError(err)
+	<-suite.headers
+
+	spans = suite.exporter.GetSpans()
+	suite.Require().Len(spans, 1)
+	suite.Require().Equal(suite.server.URL+"/pokemon?name=[REDACTED]",
+		attributes(spans[0])["url.full"].AsString())
+}
+
+func (suite *TracingTestSuite) TestMiddleware_ErrorStatus() {
+	_, err := suite.client().Get(context.Background(), "/missing", nil)
+	suite.Require().NoError(err)
+	<-suite.headers
+
+	spans := suite.exporter.GetSpans()
+	suite.Require().Len(spans, 1)
+	suite.Require().Equal(codes.Error, spans[0].Status.Code)
+	suite.Require().Equal("404", attributes(spans[0])["error.type"].AsString())
+}
+
+func (suite *TracingTestSuite) TestMiddleware_TransportError() {
+	suite.server.Close()
+
+	_, err := suite.client().Get(context.Background(), "/pokemon", nil)
+	suite.Require().Error(err)
+
+	spans := suite.exporter.GetSpans()
+	suite.Require().Len(spans, 1)
+	suite.Require().Equal(codes.Error, spans[0].Status.Code)
+	suite.Require().NotEmpty(spans[0].Events)
+}
+
+func (suite *TracingTestSuite) TestMiddleware_B3() {
+	_, err := suite.client(snorlaxotel.WithB3()).Get(context.Background(),
+		"/pokemon", nil)
+	suite.Require().NoError(err)
+
+	spans := suite.exporter.GetSpans()
+	suite.Require().Len(spans, 1)
+
+	headers := <-suite.headers
+	suite.Require().NotEmpty(headers.Get("Traceparent"))
+	suite.Require().Equal(spans[0].SpanContext.TraceID().String(),
+		headers.Get("X-B3-Traceid"))
+	suite.Require().Equal(spans[0].SpanContext.SpanID().String(),
+		headers.Get("X-B3-Spanid"))
+}
+
+func TestTracingTestSuite(t *testing.T) {
+	suite.Run(t, new(TracingTestSuite))
+}
@@ -0,0 +1,315 @@
+package snorlaxtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// Fault is a failure injected instead of a response.
+type Fault int
+
+const (
+	// NoFault serves the response normally.
+	NoFault Fault = iota
+
+	// CloseConnection closes the connection without writing a response.
+	CloseConnection
+
+	// MalformedResponse writes a response which isn't valid HTTP, and closes
+	// the connection.
+	MalformedResponse
+
+	// Hang never responds, until the client gives up on the request or the
+	// server is closed.
+	Hang
+)
+
+// Response is a canned response served by an Expectation.
+type Response struct {
+	// Status is the response's status code. If zero, 200 OK is used.
+	Status int
+
+	// Header are the response's headers.
+	Header http.Header
+
+	// Body is the response's body.
+	Body []byte
+
+	// Delay is how long the server waits before responding.
+	Delay time.Duration
+
+	// Fault is the failure injected instead of the response.
+	Fault Fault
+}
+
+// Matcher reports why a request doesn't match an Expectation, returning nil
+// if it matches.
+type Matcher func(req Request) error
+
+// Expectation describes requests the Server expects to receive, and the
+// responses it serves to them.
+//
+// By default an Expectation matches any number of requests, and must be
+// matched at least once to be met. Each response added using Respond,
+// RespondJSON, RespondWith or Fail is served in turn, and the last is repeated
+// once the sequence runs out. An Expectation without responses serves an
+// empty 200 OK.
+type Expectation struct {
+	server *Server
+
+	method    string
+	path      string
+	matchers  []Matcher
+	responses []Response
+	delay     time.Duration
+	times     int
+	calls     int
+}
+
+// String returns the method and path of requests the Expectation matches.
+func (e *Expectation) String() string {
+	return e.method + " " + e.path
+}
+
+// Query expects requests to include the query parameter with the value.
+func (e *Expectation) Query(key, value string) *Expectation {
+	return e.Match(func(req Request) error {
+		for _, v := range req.URL.Query()[key] {
+			if v == value {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("query parameter %s is %q, want %q", key,
+			req.URL.Query()[key], value)
+	})
+}
+
+// Header expects requests to include the header with the value.
+func (e *Expectation) Header(key, value string) *Expectation {
+	return e.Match(func(req Request) error {
+		for _, v := range req.Header.Values(key) {
+			if v == value {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("header %s is %q, want %q", key,
+			req.Header.Values(key), value)
+	})
+}
+
+// Body expects requests to have exactly the body.
+func (e *Expectation) Body(body string) *Expectation {
+	return e.Match(func(req Request) error {
+		if string(req.Body) != body {
+			return fmt.Errorf("body is %q, want %q", req.Body, body)
+		}
+
+		return nil
+	})
+}
+
+// JSON expects requests to have a JSON body equal to v when both are encoded
+// as JSON, ignoring formatting and the order of object keys.
+func (e *Expectation) JSON(v interface{}) *Expectation {
+	return e.jsonMatch(v, reflect.DeepEqual)
+}
+
+// PartialJSON expects requests to have a JSON body which contains v. Objects
+// in the body may have keys which aren't in v, but arrays must have the same
+// length as those in v.
+func (e *Expectation) PartialJSON(v interface{}) *Expectation {
+	return e.jsonMatch(v, containsJSON)
+}
+
+func (e *Expectation) jsonMatch(v interface{},
+	match func(got, want interface{}) bool) *Expectation {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("snorlaxtest: failed to encode JSON: %v", err))
+	}
+
+	var want interface{}
+	_ = json.Unmarshal(encoded, &want)
+
+	return e.Match(func(req Request) error {
+		var got interface{}
+		if err := json.Unmarshal(req.Body, &got); err != nil {
+			return fmt.Errorf("body is not JSON: %w", err)
+		}
+
+		if !match(got, want) {
+			return fmt.Errorf("body is %s, want %s", req.Body, encoded)
+		}
+
+		return nil
+	})
+}
+
+// Match expects requests to satisfy the Matcher.
+func (e *Expectation) Match(m Matcher) *Expectation {
+	e.server.mu.Lock()
+	defer e.server.mu.Unlock()
+
+	e.matchers = append(e.matchers, m)
+	return e
+}
+
+// Respond adds a response with the status and body to the sequence.
+func (e *Expectation) Respond(status int, body string) *Expectation {
+	return e.RespondWith(Response{Status: status, Body: []byte(body)})
+}
+
+// RespondJSON adds a response with the status and v encoded as JSON to the
+// sequence.
+func (e *Expectation) RespondJSON(status int, v interface{}) *Expectation {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("snorlaxtest: failed to encode JSON: %v", err))
+	}
+
+	return e.RespondWith(Response{
+		Status: status,
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   body,
+	})
+}
+
+// RespondWith adds the response to the sequence.
+func (e *Expectation) RespondWith(res Response) *Expectation {
+	e.server.mu.Lock()
+	defer e.server.mu.Unlock()
+
+	e.responses = append(e.responses, res)
+	return e
+}
+
+// Fail adds the Fault to the sequence, in place of a response.
+func (e *Expectation) Fail(f Fault) *Expectation {
+	return e.RespondWith(Response{Fault: f})
+}
+
+// Delay delays every response by d, in addition to the response's own Delay.
+func (e *Expectation) Delay(d time.Duration) *Expectation {
+	e.server.mu.Lock()
+	defer e.server.mu.Unlock()
+
+	e.delay = d
+	return e
+}
+
+// Times expects exactly n matching requests. Once it has matched n requests
+// the Expectation no longer matches, so further requests are matched by later
+// Expectations or reported as unexpected.
+func (e *Expectation) Times(n int) *Expectation {
+	e.server.mu.Lock()
+	defer e.server.mu.Unlock()
+
+	e.times = n
+	return e
+}
+
+// Once expects exactly one matching request.
+func (e *Expectation) Once() *Expectation {
+	return e.Times(1)
+}
+
+// Calls returns the number of requests the Expectation has matched.
+func (e *Expectation) Calls() int {
+	e.server.mu.Lock()
+	defer e.server.mu.Unlock()
+
+	return e.calls
+}
+
+// match returns why the request doesn't match the Expectation. It must be
+// called with the server's lock held.
+func (e *Expectation) match(req Request) error {
+	if e.method != req.Method || e.path != req.URL.Path {
+		return fmt.Errorf("%s: request is %s %s", e, req.Method, req.URL.Path)
+	}
+
+	for _, m := range e.matchers {
+		if err := m(req); err != nil {
+			return fmt.Errorf("%s: %w", e, err)
+		}
+	}
+
+	return nil
+}
+
+// exhausted returns whether the Expectation has matched as many requests as
+// it expects. It must be called with the server's lock held.
+func (e *Expectation) exhausted() bool {
+	return e.times > 0 && e.calls >= e.times
+}
+
+// next counts a matched request, and returns the response to serve to it. It
+// must be called with the server's lock held.
+func (e *Expectation) next() Response {
+	var res Response
+	if len(e.responses) > 0 {
+		i := e.calls
+		if i >= len(e.responses) {
+			i = len(e.responses) - 1
+		}
+		res = e.responses[i]
+	}
+
+	e.calls++
+	res.Delay += e.delay
+	return res
+}
+
+// unmet returns why the Expectation was not met. It must be called with the
+// server's lock held.
+func (e *Expectation) unmet() error {
+	switch {
+	case e.times > 0 && e.calls != e.times:
+		return fmt.Errorf("expected %d requests to %s, but got %d", e.times,
+			e, e.calls)
+	case e.calls == 0:
+		return fmt.Errorf("expected a request to %s, but got none", e)
+	}
+
+	return nil
+}
+
+// containsJSON returns whether the decoded JSON value got contains want.
+func containsJSON(got, want interface{}) bool {
+	switch want := want.(type) {
+	case map[string]interface{}:
+		got, ok := got.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		for k, v := range want {
+			if _, ok := got[k]; !ok || !containsJSON(got[k], v) {
+				return false
+			}
+		}
+
+		return true
+
+	case []interface{}:
+		got, ok := got.([]interface{})
+		if !ok || len(got) != len(want) {
+			return false
+		}
+
+		for i := range want {
+			if !containsJSON(got[i], want[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return reflect.DeepEqual(got, want)
+}
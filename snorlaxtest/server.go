@@ -0,0 +1,272 @@
+// Package snorlaxtest provides a programmable mock server for testing code
+// which uses Snorlax clients. Requests to the server are matched against
+// declarative expectations, which serve canned responses, and the server can
+// assert that every expectation was met.
+//
+//	server := snorlaxtest.NewServer()
+//	defer server.Close()
+//
+//	server.Expect(http.MethodGet, "/pokemon/143").
+//		Header("Accept", "application/json").
+//		RespondJSON(http.StatusOK, Pokemon{Name: "snorlax"})
+//
+//	res, err := server.Client().Get(ctx, "/pokemon/143", nil,
+//		snorlax.WithHeader("Accept", "application/json"))
+//	...
+//	server.AssertExpectations(t)
+package snorlaxtest
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nickcorin/snorlax"
+)
+
+// TestingT is the subset of testing.TB used to report unmet expectations.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// Request is a request received by a Server.
+type Request struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+}
+
+// String returns the request's method and target, such as "GET /pokemon?a=1".
+func (r Request) String() string {
+	return r.Method + " " + r.URL.RequestURI()
+}
+
+// Server is an HTTP server which responds to requests using the Expectations
+// registered with Expect. Requests which don't match any Expectation are
+// answered with 501 Not Implemented, and reported by AssertExpectations.
+//
+// A Server is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	requests     []Request
+	unexpected   []string
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewServer starts and returns a new Server. The caller should call Close
+// when finished, to shut it down.
+func NewServer() *Server {
+	s := Server{closed: make(chan struct{})}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+
+	return &s
+}
+
+// Close shuts down the server, releasing any requests which are delayed or
+// hanging, and blocks until all requests have completed.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+
+	s.Server.Close()
+}
+
+// Client returns a new snorlax.Client which sends requests to the server,
+// configured with the settings.
+func (s *Server) Client(settings ...snorlax.Setting) snorlax.Client {
+	return snorlax.NewClient(snorlax.Defaults()).With(append(
+		[]snorlax.Setting{
+			snorlax.UseBaseURL(s.URL),
+			snorlax.UseHTTPClient(s.Server.Client()),
+		}, settings...)...)
+}
+
+// Expect registers an Expectation for requests with the method and path, and
+// returns it to be configured further. Requests are matched against
+// Expectations in the order they are registered.
+func (s *Server) Expect(method, path string) *Expectation {
+	e := Expectation{
+		server: s,
+		method: method,
+		path:   path,
+	}
+
+	s.mu.Lock()
+	s.expectations = append(s.expectations, &e)
+	s.mu.Unlock()
+
+	return &e
+}
+
+// Requests returns the requests the server has received, in the order they
+// were received.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// Reset removes all of the server's Expectations and forgets the requests it
+// has received.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expectations = nil
+	s.requests = nil
+	s.unexpected = nil
+}
+
+// AssertExpectations reports an error to t for each Expectation which was not
+// met, and for each request which didn't match any Expectation. It returns
+// whether all Expectations were met.
+func (s *Server) AssertExpectations(t TestingT) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ok := true
+	for _, e := range s.expectations {
+		if err := e.unmet(); err != nil {
+			t.Errorf("snorlaxtest: %v", err)
+			ok = false
+		}
+	}
+
+	for _, msg := range s.unexpected {
+		t.Errorf("snorlaxtest: %s", msg)
+		ok = false
+	}
+
+	return ok
+}
+
+// serve responds to the request using the first Expectation which matches it.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	u := *r.URL
+	req := Request{
+		Method: r.Method,
+		URL:    &u,
+		Header: r.Header.Clone(),
+		Body:   body,
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+
+	var (
+		res     Response
+		matched bool
+		reasons []string
+	)
+	for _, e := range s.expectations {
+		if e.exhausted() {
+			continue
+		}
+
+		if err := e.match(req); err != nil {
+			reasons = append(reasons, err.Error())
+			continue
+		}
+
+		res, matched = e.next(), true
+		break
+	}
+
+	if !matched {
+		msg := "unexpected request " + req.String()
+		if len(reasons) > 0 {
+			msg += "\n\t" + strings.Join(reasons, "\n\t")
+		}
+		s.unexpected = append(s.unexpected, msg)
+		s.mu.Unlock()
+
+		http.Error(w, "snorlaxtest: "+msg, http.StatusNotImplemented)
+		return
+	}
+	s.mu.Unlock()
+
+	s.respond(w, r, res)
+}
+
+// respond writes the response, after waiting for its delay.
+func (s *Server) respond(w http.ResponseWriter, r *http.Request,
+	res Response) {
+	if res.Delay > 0 {
+		timer := time.NewTimer(res.Delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			return
+		case <-s.closed:
+			return
+		}
+	}
+
+	switch res.Fault {
+	case Hang:
+		select {
+		case <-r.Context().Done():
+		case <-s.closed:
+		}
+		return
+
+	case CloseConnection, MalformedResponse:
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+
+		if res.Fault == MalformedResponse {
+			writeMalformed(buf)
+		}
+		return
+	}
+
+	for k, values := range res.Header {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+
+	status := res.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	w.WriteHeader(status)
+	_, _ = w.Write(res.Body)
+}
+
+// writeMalformed writes a response which isn't valid HTTP.
+func writeMalformed(buf *bufio.ReadWriter) {
+	_, _ = fmt.Fprint(buf, "SNORLAX/1.0 zzz\r\n\r\n")
+	_ = buf.Flush()
+}
@@ -0,0 +1,176 @@
+package snorlaxtest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/nickcorin/snorlax/snorlaxtest"
+	"github.com/stretchr/testify/suite"
+)
+
+// recorder is a snorlaxtest.TestingT which records the errors reported to it.
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+type ServerTestSuite struct {
+	suite.Suite
+	server *snorlaxtest.Server
+	client snorlax.Client
+}
+
+func (suite *ServerTestSuite) SetupTest() {
+	suite.server = snorlaxtest.NewServer()
+	suite.client = suite.server.Client()
+}
+
+func (suite *ServerTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *ServerTestSuite) TestMatchers() {
+	type Pokemon struct {
+		Name   string `json:"name"`
+		Number int    `json:"number"`
+	}
+
+	suite.server.Expect(http.MethodPost, "/pokemon").
+		Query("region", "kanto").
+		Header("X-Trainer", "red").
+		JSON(Pokemon{Name: "snorlax", Number: 143}).
+		RespondJSON(http.StatusCreated, Pokemon{Name: "snorlax", Number: 143})
+
+	suite.server.Expect(http.MethodPost, "/pokemon").
+		PartialJSON(map[string]string{"name": "mew"}).
+		Respond(http.StatusConflict, "")
+
+	res, err := suite.client.Post(context.TODO(), "/pokemon",
+		map[string][]string{"region": {"kanto"}},
+		strings.NewReader(`{"number": 143, "name": "snorlax"}`),
+		snorlax.WithHeader("X-Trainer", "red"))
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusCreated, res.StatusCode)
+	suite.Require().Equal("application/json", res.Header.Get("Content-Type"))
+
+	var pokemon Pokemon
+	suite.Require().NoError(res.JSON(&pokemon))
+	suite.Require().Equal("snorlax", pokemon.Name)
+
+	res, err = suite.client.Post(context.TODO(), "/pokemon", nil,
+		strings.NewReader(`{"number": 151, "name": "mew"}`))
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusConflict, res.StatusCode)
+
+	suite.Require().True(suite.server.AssertExpectations(suite.T()))
+	suite.Require().Len(suite.server.Requests(), 2)
+	suite.Require().Equal(`{"number": 151, "name": "mew"}`,
+		string(suite.server.Requests()[1].Body))
+}
+
+func (suite *ServerTestSuite) TestSequence() {
+	e := suite.server.Expect(http.MethodGet, "/pokemon").
+		Respond(http.StatusServiceUnavailable, "").
+		Respond(http.StatusOK, "snorlax")
+
+	statuses := make([]int, 3)
+	for i := range statuses {
+		res, err := suite.client.Get(context.TODO(), "/pokemon", nil)
+		suite.Require().NoError(err)
+		statuses[i] = res.StatusCode
+	}
+
+	suite.Require().Equal([]int{http.StatusServiceUnavailable,
+		http.StatusOK, http.StatusOK}, statuses)
+	suite.Require().Equal(3, e.Calls())
+}
+
+func (suite *ServerTestSuite) TestFaults() {
+	suite.server.Expect(http.MethodGet, "/closed").
+		Fail(snorlaxtest.CloseConnection)
+	suite.server.Expect(http.MethodGet, "/malformed").
+		Fail(snorlaxtest.MalformedResponse)
+	suite.server.Expect(http.MethodGet, "/hang").
+		Fail(snorlaxtest.Hang)
+
+	_, err := suite.client.Get(context.TODO(), "/closed", nil)
+	suite.Require().Error(err)
+
+	_, err = suite.client.Get(context.TODO(), "/malformed", nil)
+	suite.Require().Error(err)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = suite.client.Get(ctx, "/hang", nil)
+	suite.Require().True(errors.Is(err, context.DeadlineExceeded))
+}
+
+func (suite *ServerTestSuite) TestDelay() {
+	suite.server.Expect(http.MethodGet, "/slow").
+		Delay(50*time.Millisecond).
+		Respond(http.StatusOK, "")
+
+	start := time.Now()
+	_, err := suite.client.Get(context.TODO(), "/slow", nil)
+	suite.Require().NoError(err)
+	suite.Require().True(time.Since(start) >= 50*time.Millisecond)
+}
+
+func (suite *ServerTestSuite) TestRetries() {
+	suite.server.Expect(http.MethodGet, "/pokemon").
+		Fail(snorlaxtest.CloseConnection).
+		Respond(http.StatusOK, "snorlax").
+		Times(2)
+
+	policy := snorlax.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+
+	res, err := suite.client.SetRetryPolicy(policy).
+		Get(context.TODO(), "/pokemon", nil)
+	suite.Require().NoError(err)
+
+	body, err := ioutil.ReadAll(res.Body)
+	suite.Require().NoError(err)
+	suite.Require().Equal("snorlax", string(body))
+	suite.Require().True(suite.server.AssertExpectations(suite.T()))
+}
+
+func (suite *ServerTestSuite) TestAssertExpectations() {
+	suite.server.Expect(http.MethodGet, "/pokemon").
+		Header("X-Trainer", "red")
+	suite.server.Expect(http.MethodDelete, "/pokemon")
+
+	_, err := suite.client.Get(context.TODO(), "/pokemon", nil,
+		snorlax.WithHeader("X-Trainer", "red"))
+	suite.Require().NoError(err)
+
+	res, err := suite.client.Get(context.TODO(), "/pokemon", nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusNotImplemented, res.StatusCode)
+
+	var r recorder
+	suite.Require().False(suite.server.AssertExpectations(&r))
+	suite.Require().Len(r.errors, 2)
+	suite.Require().Contains(r.errors[0], "DELETE /pokemon")
+	suite.Require().Contains(r.errors[1], "unexpected request GET /pokemon")
+	suite.Require().Contains(r.errors[1], `header X-Trainer is [], want "red"`)
+
+	suite.server.Reset()
+	suite.Require().True(suite.server.AssertExpectations(suite.T()))
+	suite.Require().Empty(suite.server.Requests())
+}
+
+func TestServerTestSuite(t *testing.T) {
+	suite.Run(t, new(ServerTestSuite))
+}
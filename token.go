@@ -0,0 +1,101 @@
+package snorlax
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenProvider provides a credential, such as a bearer token or API key, for
+// each request. Providers are called for every request, so that credentials
+// can be rotated without rebuilding the client. Wrap slow providers using
+// CachedToken.
+type TokenProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenProviderFunc is an adapter which allows ordinary functions to be used
+// as TokenProviders.
+type TokenProviderFunc func(ctx context.Context) (string, error)
+
+// Token calls f(ctx).
+func (f TokenProviderFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// StaticToken returns a TokenProvider which always provides token.
+func StaticToken(token string) TokenProvider {
+	return TokenProviderFunc(func(context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// EnvToken returns a TokenProvider which reads the token from the environment
+// variable name. An unset or empty variable is an error.
+func EnvToken(name string) TokenProvider {
+	return TokenProviderFunc(func(context.Context) (string, error) {
+		token := os.Getenv(name)
+		if token == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+
+		return token, nil
+	})
+}
+
+// FileToken returns a TokenProvider which reads the token from the file at
+// path, ignoring leading and trailing whitespace. This suits secrets which are
+// mounted as files and rotated in place. An empty file is an error.
+func FileToken(path string) TokenProvider {
+	return TokenProviderFunc(func(context.Context) (string, error) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file: %w", err)
+		}
+
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("token file %s is empty", path)
+		}
+
+		return token, nil
+	})
+}
+
+// CachedToken returns a TokenProvider which caches the tokens provided by p for
+// ttl. Errors are not cached, so a failed call is retried on the next request.
+func CachedToken(p TokenProvider, ttl time.Duration) TokenProvider {
+	return &cachedToken{provider: p, ttl: ttl}
+}
+
+type cachedToken struct {
+	provider TokenProvider
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Token satisfies the TokenProvider interface. Concurrent callers wait for a
+// single call to the underlying provider.
+func (c *cachedToken) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Now().Before(c.expires) {
+		return c.token, nil
+	}
+
+	token, err := c.provider.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	c.token, c.expires = token, time.Now().Add(c.ttl)
+	return token, nil
+}
@@ -0,0 +1,106 @@
+package snorlax_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nickcorin/snorlax"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvToken(t *testing.T) {
+	p := snorlax.EnvToken("SNORLAX_TEST_TOKEN")
+
+	_, err := p.Token(context.TODO())
+	require.Error(t, err)
+
+	t.Setenv("SNORLAX_TEST_TOKEN", "first")
+	token, err := p.Token(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, "first", token)
+
+	t.Setenv("SNORLAX_TEST_TOKEN", "second")
+	token, err = p.Token(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, "second", token)
+}
+
+func TestFileToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	p := snorlax.FileToken(path)
+
+	_, err := p.Token(context.TODO())
+	require.True(t, errors.Is(err, os.ErrNotExist))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("first\n"), 0600))
+	token, err := p.Token(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, "first", token)
+
+	// Rotating the file rotates the token.
+	require.NoError(t, ioutil.WriteFile(path, []byte("second\n"), 0600))
+	token, err = p.Token(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, "second", token)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(" \n"), 0600))
+	_, err = p.Token(context.TODO())
+	require.Error(t, err)
+}
+
+func TestCachedToken(t *testing.T) {
+	var calls int32
+	fail := int32(1)
+	p := snorlax.CachedToken(snorlax.TokenProviderFunc(
+		func(context.Context) (string, error) {
+			if atomic.CompareAndSwapInt32(&fail, 1, 0) {
+				return "", errors.New("vault sealed")
+			}
+			atomic.AddInt32(&calls, 1)
+			return "s3cr3t", nil
+		}), 50*time.Millisecond)
+
+	// Errors are not cached.
+	_, err := p.Token(context.TODO())
+	require.Error(t, err)
+
+	for i := 0; i < 3; i++ {
+		token, err := p.Token(context.TODO())
+		require.NoError(t, err)
+		require.Equal(t, "s3cr3t", token)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = p.Token(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestClient_TokenProviderError(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+		}))
+	defer server.Close()
+
+	client := snorlax.NewClient(snorlax.Defaults()).
+		SetBaseURL(server.URL).
+		AddRequestHook(snorlax.WithAPIKeyProvider("X-Api-Key",
+			snorlax.EnvToken("SNORLAX_TEST_MISSING"), snorlax.APIKeyInHeader))
+
+	res, err := client.Get(context.TODO(), "/", nil)
+	require.Error(t, err)
+	require.Nil(t, res)
+	require.Contains(t, err.Error(), "SNORLAX_TEST_MISSING")
+	require.Equal(t, int32(0), atomic.LoadInt32(&requests))
+}